@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Klaus-Tockloth/dagote/dagote"
+	toml "github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// default names of project file (searched in current directory)
+var projectFiles = []string{"dagote.yaml", "dagote.yml", "dagote.toml"}
+
+/*
+projectConfig describes a project file with render jobs.
+*/
+type projectConfig struct {
+	Jobs []projectJob `yaml:"jobs" toml:"jobs"`
+}
+
+/*
+projectJob describes one render job (corresponds to one invocation of this program).
+*/
+type projectJob struct {
+	Name           string   `yaml:"name" toml:"name"`
+	Templates      string   `yaml:"templates" toml:"templates"`
+	Format         string   `yaml:"format" toml:"format"`
+	Output         string   `yaml:"output" toml:"output"`
+	DotFiles       []string `yaml:"dotfiles" toml:"dotfiles"`
+	DotString      string   `yaml:"dotstring" toml:"dotstring"`
+	DotTypes       []string `yaml:"dottypes" toml:"dottypes"`
+	DotOpts        []string `yaml:"dotopts" toml:"dotopts"`
+	DotMerge       string   `yaml:"dotmerge" toml:"dotmerge"`
+	DotKey         string   `yaml:"dotkey" toml:"dotkey"`
+	Fanout         bool     `yaml:"fanout" toml:"fanout"`
+	TemplateDir    string   `yaml:"templatedir" toml:"templatedir"`
+	OutputDir      string   `yaml:"outputdir" toml:"outputdir"`
+	Partials       string   `yaml:"partials" toml:"partials"`
+	Copy           string   `yaml:"copy" toml:"copy"`
+	OutputRoot     string   `yaml:"outputroot" toml:"outputroot"`
+	LeftDelim      string   `yaml:"leftdelim" toml:"leftdelim"`
+	RightDelim     string   `yaml:"rightdelim" toml:"rightdelim"`
+	Functions      []string `yaml:"functions" toml:"functions"`
+	DataPath       string   `yaml:"datapath" toml:"datapath"`
+	DataDir        string   `yaml:"datadir" toml:"datadir"`
+	AllowRead      []string `yaml:"allow-read" toml:"allow-read"`
+	Timeout        string   `yaml:"timeout" toml:"timeout"`
+	MaxOutputBytes int64    `yaml:"max-output-bytes" toml:"max-output-bytes"`
+	MaxReadBytes   int64    `yaml:"max-read-bytes" toml:"max-read-bytes"`
+	PartialOutput  string   `yaml:"partial-output" toml:"partial-output"`
+	Parallel       int      `yaml:"parallel" toml:"parallel"`
+}
+
+/*
+jobResult describes the result of one render job.
+*/
+type jobResult struct {
+	name     string
+	duration time.Duration
+	err      error
+}
+
+/*
+build executes the named jobs (all jobs if no name given) of the project file.
+*/
+func build(opts *options, filename string, jobNames []string) error {
+	var err error
+
+	if filename == "" {
+		filename, err = findProjectFile()
+		if err != nil {
+			return err
+		}
+	}
+	config, err := readProjectFile(filename)
+	if err != nil {
+		return err
+	}
+
+	jobs, err := selectJobs(config.Jobs, jobNames)
+	if err != nil {
+		return fmt.Errorf("%w, file=[%v]", err, filename)
+	}
+
+	// relative paths are resolved against the directory of the project file
+	projectDir := filepath.Dir(filename)
+
+	// data files are parsed only once (shared by all jobs)
+	cache := dagote.NewDataCache()
+
+	workers := numWorkers(opts.parallel, len(jobs))
+	fmt.Fprintf(opts.logWriter, "Project file [%s] (%d job(s) selected, %d worker(s)):\n", filename, len(jobs), workers)
+	results := make([]jobResult, len(jobs))
+	_ = runParallel(opts.logWriter, workers, len(jobs), func(worker, index int, logWriter io.Writer) error {
+		job := jobs[index]
+		fmt.Fprintf(logWriter, "\n=== Job [%s] ===\n", job.Name)
+		start := time.Now()
+		// number of workers and execution limits given on command line apply to all jobs without own setting
+		jobOpts, err := jobOptions(job, opts)
+		if err == nil {
+			resolveJobPaths(jobOpts, projectDir)
+			jobOpts.logWriter = logWriter
+			jobOpts.cache = cache
+			err = runJob(jobOpts)
+		}
+		if err != nil {
+			fmt.Fprintf(logWriter, "error: %v\n", err)
+		}
+		results[index] = jobResult{name: job.Name, duration: time.Since(start), err: err}
+		// failed jobs don't stop the remaining jobs
+		return nil
+	})
+
+	failed := 0
+	for _, result := range results {
+		if result.err != nil {
+			failed++
+		}
+	}
+	printJobSummary(opts.logWriter, results)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d job(s) failed", failed, len(results))
+	}
+	return nil
+}
+
+/*
+findProjectFile searches the default project file in the current directory.
+*/
+func findProjectFile() (string, error) {
+	for _, filename := range projectFiles {
+		if _, err := os.Stat(filename); err == nil {
+			return filename, nil
+		}
+	}
+	return "", fmt.Errorf("no project file found, expected one of %v", projectFiles)
+}
+
+/*
+readProjectFile reads project file (TOML for extension '.toml', otherwise YAML), unknown keys are rejected.
+*/
+func readProjectFile(filename string) (projectConfig, error) {
+	var config projectConfig
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return config, fmt.Errorf("unable to read project file, file=[%v], error=[%w]", filename, err)
+	}
+	if strings.ToLower(filepath.Ext(filename)) == ".toml" {
+		decoder := toml.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&config)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&config)
+	}
+	if err != nil {
+		return config, fmt.Errorf("unable to unmarshal project file, file=[%v], error=[%w]", filename, err)
+	}
+
+	if len(config.Jobs) == 0 {
+		return config, fmt.Errorf("project file without jobs, file=[%v]", filename)
+	}
+	names := make(map[string]bool)
+	for i, job := range config.Jobs {
+		if job.Name == "" {
+			return config, fmt.Errorf("job without name, file=[%v], job=[%d]", filename, i+1)
+		}
+		if names[job.Name] {
+			return config, fmt.Errorf("job name not unique, file=[%v], job=[%v]", filename, job.Name)
+		}
+		names[job.Name] = true
+	}
+	return config, nil
+}
+
+/*
+selectJobs selects jobs by name (in order of names), all jobs if no name given.
+*/
+func selectJobs(jobs []projectJob, names []string) ([]projectJob, error) {
+	if len(names) == 0 {
+		return jobs, nil
+	}
+	var selected []projectJob
+	for _, name := range names {
+		found := false
+		for _, job := range jobs {
+			if job.Name == name {
+				selected = append(selected, job)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("job not found in project file, job=[%v]", name)
+		}
+	}
+	return selected, nil
+}
+
+/*
+runJob validates job options and runs the job.
+*/
+func runJob(opts *options) error {
+	if opts.stdinUsed() {
+		return errors.New("stdin ('-') not supported in project file")
+	}
+	err := opts.validate()
+	if err != nil {
+		return err
+	}
+	_, err = run(opts)
+	return err
+}
+
+/*
+jobOptions creates options from job settings (defaults for unset values, command line settings for
+number of workers and execution limits).
+*/
+func jobOptions(job projectJob, cli *options) (*options, error) {
+	opts := newOptions()
+	opts.templates = job.Templates
+	opts.format = defaultString(job.Format, opts.format)
+	opts.outputFile = job.Output
+	opts.dotfiles = stringList(job.DotFiles)
+	opts.dotstring = job.DotString
+	opts.dottypes = stringList(job.DotTypes)
+	opts.dotopts = stringList(job.DotOpts)
+	opts.dotmerge = defaultString(job.DotMerge, opts.dotmerge)
+	opts.dotkey = defaultString(job.DotKey, opts.dotkey)
+	opts.fanout = job.Fanout
+	opts.templateDir = job.TemplateDir
+	opts.outputDir = job.OutputDir
+	opts.partials = job.Partials
+	opts.copyGlobs = job.Copy
+	opts.outputRoot = job.OutputRoot
+	opts.leftDelim = defaultString(job.LeftDelim, opts.leftDelim)
+	opts.rightDelim = defaultString(job.RightDelim, opts.rightDelim)
+	opts.functions = defaultString(strings.Join(job.Functions, ","), opts.functions)
+	opts.dataPath = defaultString(job.DataPath, opts.dataPath)
+	opts.dataDir = job.DataDir
+	opts.allowRead = strings.Join(job.AllowRead, ",")
+	opts.parallel = cli.parallel
+	if job.Parallel != 0 {
+		opts.parallel = job.Parallel
+	}
+	opts.timeout = cli.timeout
+	if job.Timeout != "" {
+		timeout, err := time.ParseDuration(job.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout, timeout=[%v], error=[%w]", job.Timeout, err)
+		}
+		opts.timeout = timeout
+	}
+	opts.maxOutputBytes = cli.maxOutputBytes
+	if job.MaxOutputBytes != 0 {
+		opts.maxOutputBytes = job.MaxOutputBytes
+	}
+	opts.maxReadBytes = cli.maxReadBytes
+	if job.MaxReadBytes != 0 {
+		opts.maxReadBytes = job.MaxReadBytes
+	}
+	opts.partialOutput = defaultString(job.PartialOutput, cli.partialOutput)
+	return opts, nil
+}
+
+/*
+resolveJobPaths resolves relative paths of job options against the project directory (the working directory
+of the process is not changed, jobs may run in parallel). Data functions of jobs with data path mode 'cwd'
+resolve relative paths against the project directory.
+*/
+func resolveJobPaths(opts *options, dir string) {
+	if dir == "." {
+		return
+	}
+	// default output root is determined before resolving (current working directory -> project directory)
+	opts.outputRoot = joinPath(dir, opts.determineOutputRoot())
+	opts.templates = joinPathList(dir, opts.templates)
+	opts.partials = joinPathList(dir, opts.partials)
+	opts.allowRead = joinPathList(dir, opts.allowRead)
+	for i, arg := range opts.dotfiles {
+		if dottype, filename := splitDotFile(arg); dottype != "" {
+			opts.dotfiles[i] = dottype + ":" + joinPath(dir, filename)
+		} else {
+			opts.dotfiles[i] = joinPath(dir, arg)
+		}
+	}
+	opts.outputFile = joinPath(dir, opts.outputFile)
+	opts.templateDir = joinPath(dir, opts.templateDir)
+	opts.outputDir = joinPath(dir, opts.outputDir)
+	opts.dataDir = joinPath(dir, opts.dataDir)
+	if opts.dataPathMode() == "cwd" {
+		opts.dataPath = "datadir"
+		opts.dataDir = dir
+	}
+}
+
+/*
+joinPathList resolves each relative path of comma separated list against directory.
+*/
+func joinPathList(dir, list string) string {
+	paths := splitList(list)
+	for i := range paths {
+		paths[i] = joinPath(dir, paths[i])
+	}
+	return strings.Join(paths, ",")
+}
+
+/*
+joinPath resolves relative path (also path of zip archive in 'bundle.zip!path') against directory,
+empty paths, stdin/stdout ('-') and absolute paths are returned unchanged.
+*/
+func joinPath(dir, path string) string {
+	if path == "" || path == "-" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+/*
+defaultString returns value or (if empty) default value.
+*/
+func defaultString(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+/*
+printJobSummary prints a table with the results of all jobs.
+*/
+func printJobSummary(logWriter io.Writer, results []jobResult) {
+	fmt.Fprintf(logWriter, "\nJob summary:\n")
+	writer := tabwriter.NewWriter(logWriter, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "  JOB\tRESULT\tDURATION\tERROR\n")
+	for _, result := range results {
+		status := "ok"
+		message := ""
+		if result.err != nil {
+			status = "failed"
+			message = result.err.Error()
+		}
+		fmt.Fprintf(writer, "  %s\t%s\t%s\t%s\n", result.name, status, result.duration.Round(time.Millisecond), message)
+	}
+	writer.Flush()
+	fmt.Fprintf(logWriter, "\n")
+}
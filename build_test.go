@@ -0,0 +1,91 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+/*
+TestResolveJobPaths tests the resolution of relative job paths against the project directory.
+*/
+func TestResolveJobPaths(t *testing.T) {
+	abs, _ := filepath.Abs("/abs")
+	tests := []struct {
+		name   string
+		dir    string
+		modify func(opts *options)
+		check  func(opts *options) []string
+		want   []string
+	}{
+		{
+			"template and dot files",
+			"proj",
+			func(opts *options) {
+				opts.templates = "tpl/a.tmpl,bundle.zip!t/*.tmpl"
+				opts.dotfiles = []string{"data/dot.json", abs}
+			},
+			func(opts *options) []string { return append([]string{opts.templates}, opts.dotfiles...) },
+			[]string{filepath.Join("proj", "tpl/a.tmpl") + "," + filepath.Join("proj", "bundle.zip!t/*.tmpl"), filepath.Join("proj", "data/dot.json"), abs},
+		},
+		{
+			"output file and default output root",
+			"proj",
+			func(opts *options) { opts.outputFile = "out/page.html" },
+			func(opts *options) []string { return []string{opts.outputFile, opts.outputRoot} },
+			[]string{filepath.Join("proj", "out/page.html"), filepath.Join("proj", "out")},
+		},
+		{
+			"stdout output",
+			"proj",
+			func(opts *options) { opts.outputFile = "-" },
+			func(opts *options) []string { return []string{opts.outputFile, opts.outputRoot} },
+			[]string{"-", "proj"},
+		},
+		{
+			"template directory",
+			"proj",
+			func(opts *options) { opts.templateDir, opts.outputDir = "skel", "site" },
+			func(opts *options) []string { return []string{opts.templateDir, opts.outputDir, opts.outputRoot} },
+			[]string{filepath.Join("proj", "skel"), filepath.Join("proj", "site"), filepath.Join("proj", "site")},
+		},
+		{
+			"data path mode cwd",
+			"proj",
+			func(opts *options) {},
+			func(opts *options) []string { return []string{opts.dataPathMode(), opts.dataDir} },
+			[]string{"datadir", "proj"},
+		},
+		{
+			"data path mode start",
+			"proj",
+			func(opts *options) { opts.dataPath, opts.allowRead = "start", "shared" },
+			func(opts *options) []string { return []string{opts.dataPathMode(), opts.dataDir, opts.allowRead} },
+			[]string{"start", "", filepath.Join("proj", "shared")},
+		},
+		{
+			"data directory",
+			"proj",
+			func(opts *options) { opts.dataDir = "data" },
+			func(opts *options) []string { return []string{opts.dataPathMode(), opts.dataDir} },
+			[]string{"datadir", filepath.Join("proj", "data")},
+		},
+		{
+			"project in current directory",
+			".",
+			func(opts *options) { opts.templates, opts.outputFile = "a.tmpl", "out.txt" },
+			func(opts *options) []string { return []string{opts.templates, opts.outputFile, opts.dataPathMode()} },
+			[]string{"a.tmpl", "out.txt", "cwd"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := newOptions()
+			tt.modify(opts)
+			resolveJobPaths(opts, tt.dir)
+			if got := tt.check(opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveJobPaths(%q) = %q, want %q", tt.dir, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/Klaus-Tockloth/dagote/dagote"
+)
+
+/*
+bundleSet holds the zip archives (template bundles) referenced by template lists ('bundle.zip!templates/*.tmpl').
+*/
+type bundleSet struct {
+	names    []string // archive names in order of first reference
+	archives map[string]fs.FS
+}
+
+/*
+openBundles opens all zip archives referenced by the template lists.
+*/
+func openBundles(lists ...string) (*bundleSet, error) {
+	bundles := &bundleSet{archives: make(map[string]fs.FS)}
+	for _, list := range lists {
+		if list == "" {
+			continue
+		}
+		for _, entry := range strings.Split(list, ",") {
+			archive, _, ok := dagote.SplitArchivePath(entry)
+			if !ok || bundles.archives[archive] != nil {
+				continue
+			}
+			fsys, err := dagote.OpenZipFS(archive)
+			if err != nil {
+				return nil, err
+			}
+			bundles.names = append(bundles.names, archive)
+			bundles.archives[archive] = fsys
+		}
+	}
+	return bundles, nil
+}
+
+/*
+overlay returns the file system for data loaders: all archives (in order of reference), then the current directory.
+Returns nil if no archive is used (data loaders use the local file system).
+*/
+func (b *bundleSet) overlay() fs.FS {
+	if b == nil || len(b.names) == 0 {
+		return nil
+	}
+	var layers []fs.FS
+	for _, name := range b.names {
+		layers = append(layers, b.archives[name])
+	}
+	layers = append(layers, os.DirFS("."))
+	return dagote.NewOverlayFS(layers...)
+}
+
+/*
+archive returns the file system of a (referenced) zip archive.
+*/
+func (b *bundleSet) archive(name string) (fs.FS, error) {
+	if b != nil {
+		if fsys, ok := b.archives[name]; ok {
+			return fsys, nil
+		}
+	}
+	return nil, fmt.Errorf("zip archive not opened, file=[%v]", name)
+}
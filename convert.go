@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Klaus-Tockloth/dagote/dagote"
+)
+
+/*
+convertOptions describes the settings of the command 'convert'.
+*/
+type convertOptions struct {
+	input    string
+	output   string
+	from     string
+	to       string
+	pretty   bool
+	sortKeys bool
+	header   string
+	root     string
+}
+
+/*
+convert reads data from input file, converts it into output format and writes it to output file.
+*/
+func convert(opts *options, copts *convertOptions) error {
+	if copts.input == "" {
+		return errors.New("option '-input=file' required for command 'convert'")
+	}
+	if copts.output == "" {
+		return errors.New("option '-output=file' required for command 'convert'")
+	}
+
+	from := strings.ToLower(copts.from)
+	if from == "" {
+		from = "auto"
+	}
+	if !isDotType(from) {
+		return fmt.Errorf("option '-from=%s' not supported", copts.from)
+	}
+	to := strings.ToLower(copts.to)
+	if to == "" {
+		to = outputTypeByExtension(copts.output)
+		if to == "" {
+			return errors.New("option '-to=type' required (output type not detectable by file extension)")
+		}
+	}
+	supported := false
+	for _, convertType := range dagote.ConvertTypes {
+		supported = supported || to == convertType
+	}
+	if !supported {
+		return fmt.Errorf("option '-to=%s' not supported", copts.to)
+	}
+
+	dotOpts, err := dotOptions(opts.dotopts)
+	if err != nil {
+		return err
+	}
+	csvOpts, err := dagote.ParseCSVOptions(dotOpts)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if copts.input == "-" {
+		data, err = readStdin()
+		if err != nil {
+			return fmt.Errorf("-input: unable to read stdin, error=[%w]", err)
+		}
+	} else {
+		data, err = os.ReadFile(copts.input)
+		if err != nil {
+			return fmt.Errorf("unable to read input file, file=[%v], error=[%w]", copts.input, err)
+		}
+		if from == "auto" {
+			from = dagote.DetectTypeByExtension(copts.input)
+		}
+	}
+	if from == "" || from == "auto" {
+		from = dagote.SniffType(data)
+	}
+
+	output, err := dagote.Convert(data, from, to, dagote.ConvertOptions{
+		Pretty:   copts.pretty,
+		SortKeys: copts.sortKeys,
+		Header:   splitList(copts.header),
+		Root:     copts.root,
+		CSV:      csvOpts,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to convert data (%s -> %s), input=[%v], error=[%w]", from, to, copts.input, err)
+	}
+
+	if copts.output == "-" {
+		_, err = os.Stdout.Write(output)
+		return err
+	}
+	err = os.WriteFile(copts.output, output, 0666)
+	if err != nil {
+		return fmt.Errorf("unable to write output file, file=[%v], error=[%w]", copts.output, err)
+	}
+	fmt.Fprintf(opts.logWriter, "Converted [%s] (%s) -> [%s] (%s)\n", copts.input, from, copts.output, to)
+	return nil
+}
+
+/*
+outputTypeByExtension detects the output type of a file by its extension (empty string if unknown).
+*/
+func outputTypeByExtension(filename string) string {
+	if filepath.Ext(filename) == ".txt" {
+		return "text"
+	}
+	return dagote.DetectTypeByExtension(filename)
+}
@@ -0,0 +1,130 @@
+package dagote
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+)
+
+/*
+cachedData describes parsed data of a file (valid as long as file is unchanged).
+*/
+type cachedData struct {
+	size    int64
+	modTime time.Time
+	value   any
+}
+
+/*
+DataCache caches parsed data files (safe for concurrent use, e.g. shared by multiple engines).
+Maps and slices are copied on store and lookup, so modifications of returned data (e.g. by sprig 'set')
+don't leak into other executions or jobs.
+*/
+type DataCache struct {
+	mu    sync.Mutex
+	files map[string]cachedData
+}
+
+/*
+NewDataCache creates a data cache.
+*/
+func NewDataCache() *DataCache {
+	return &DataCache{files: make(map[string]cachedData)}
+}
+
+/*
+cacheKey builds cache key from kind of data and absolute file name.
+*/
+func cacheKey(kind, filename string) string {
+	if absPath, err := filepath.Abs(filename); err == nil {
+		filename = absPath
+	}
+	return kind + ":" + filename
+}
+
+/*
+lookup returns parsed data of file (if cached, file unchanged and file size within read limit, 0 = no limit).
+Files exceeding the read limit are not returned, so that reading them again fails with the read limit error.
+*/
+func (c *DataCache) lookup(kind, filename string, maxReadBytes int64) (any, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.files[cacheKey(kind, filename)]
+	if !ok {
+		return nil, false
+	}
+	info, err := os.Stat(filename)
+	if err != nil || info.Size() != cached.size || !info.ModTime().Equal(cached.modTime) {
+		return nil, false
+	}
+	if maxReadBytes > 0 && cached.size > maxReadBytes {
+		return nil, false
+	}
+	return copyData(cached.value), true
+}
+
+/*
+store stores parsed data of file.
+*/
+func (c *DataCache) store(kind, filename string, value any) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	info, err := os.Stat(filename)
+	if err != nil {
+		return
+	}
+	c.files[cacheKey(kind, filename)] = cachedData{size: info.Size(), modTime: info.ModTime(), value: copyData(value)}
+}
+
+/*
+copyData returns a deep copy of the maps and slices of data (other values are immutable or shared, e.g. XML documents).
+*/
+func copyData(data any) any {
+	if data == nil {
+		return nil
+	}
+	return copyValue(reflect.ValueOf(data)).Interface()
+}
+
+/*
+copyValue returns a deep copy of maps and slices (recursively).
+*/
+func copyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(copyValue(v.Elem()))
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		m := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m.SetMapIndex(iter.Key(), copyValue(iter.Value()))
+		}
+		return m
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		s := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s.Index(i).Set(copyValue(v.Index(i)))
+		}
+		return s
+	}
+	return v
+}
@@ -0,0 +1,356 @@
+package dagote
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+/*
+collectionFuncs returns the functions of group 'collection'.
+*/
+func collectionFuncs() map[string]any {
+	return map[string]any{
+		"sortBy":   SortBy,
+		"groupBy":  GroupBy,
+		"where":    Where,
+		"pluck":    Pluck,
+		"uniqueBy": UniqueBy,
+		"sumBy":    SumBy,
+		"chunk":    Chunk,
+	}
+}
+
+/*
+SortBy sorts list of records (maps) by comma-separated keys, prefix '-' sorts descending
+(e.g. sortBy "dept,-salary" $rows). The sort is stable and numeric-aware.
+*/
+func SortBy(keys string, list any) ([]any, error) {
+	records, err := toRecords("sortBy", list)
+	if err != nil {
+		return nil, err
+	}
+	type sortKey struct {
+		name       string
+		descending bool
+	}
+	var sortKeys []sortKey
+	for _, key := range strings.Split(keys, ",") {
+		key = strings.TrimSpace(key)
+		descending := strings.HasPrefix(key, "-")
+		key = strings.TrimPrefix(key, "-")
+		if key == "" {
+			return nil, fmt.Errorf("sortBy needs a key, keys=[%v]", keys)
+		}
+		sortKeys = append(sortKeys, sortKey{name: key, descending: descending})
+	}
+
+	result := make([]any, len(records))
+	copy(result, records)
+	sort.SliceStable(result, func(i, j int) bool {
+		for _, key := range sortKeys {
+			c := CompareValues(fieldValue(result[i], key.name), fieldValue(result[j], key.name))
+			if c == 0 {
+				continue
+			}
+			if key.descending {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	return result, nil
+}
+
+/*
+GroupBy groups list of records (maps) by value of key (e.g. groupBy "dept" $rows).
+The groups keep the order of the records. Ranging over the result yields the groups sorted by key value.
+*/
+func GroupBy(key string, list any) (map[string][]any, error) {
+	records, err := toRecords("groupBy", list)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string][]any)
+	for _, record := range records {
+		value := valueString(fieldValue(record, key))
+		groups[value] = append(groups[value], record)
+	}
+	return groups, nil
+}
+
+/*
+Where returns the records (maps) whose value of key matches (e.g. where "active" "true" $rows,
+where "age" ">=" 18 $rows). Operators: ==, !=, <, <=, >, >=, contains (numeric-aware).
+*/
+func Where(key string, args ...any) ([]any, error) {
+	var operator string
+	var value, list any
+	switch len(args) {
+	case 2:
+		operator, value, list = "==", args[0], args[1]
+	case 3:
+		operator, value, list = fmt.Sprint(args[0]), args[1], args[2]
+	default:
+		return nil, fmt.Errorf("where needs key, [operator,] value and list, arguments=[%d]", len(args)+1)
+	}
+	records, err := toRecords("where", list)
+	if err != nil {
+		return nil, err
+	}
+
+	var match func(fieldValue any) bool
+	switch operator {
+	case "==", "=", "eq":
+		match = func(v any) bool { return CompareValues(v, value) == 0 }
+	case "!=", "ne":
+		match = func(v any) bool { return CompareValues(v, value) != 0 }
+	case "<", "lt":
+		match = func(v any) bool { return v != nil && CompareValues(v, value) < 0 }
+	case "<=", "le":
+		match = func(v any) bool { return v != nil && CompareValues(v, value) <= 0 }
+	case ">", "gt":
+		match = func(v any) bool { return v != nil && CompareValues(v, value) > 0 }
+	case ">=", "ge":
+		match = func(v any) bool { return v != nil && CompareValues(v, value) >= 0 }
+	case "contains":
+		match = func(v any) bool { return v != nil && strings.Contains(valueString(v), valueString(value)) }
+	default:
+		return nil, fmt.Errorf("where: unsupported operator, operator=[%v], supported=[==, !=, <, <=, >, >=, contains]", operator)
+	}
+
+	result := []any{}
+	for _, record := range records {
+		if match(fieldValue(record, key)) {
+			result = append(result, record)
+		}
+	}
+	return result, nil
+}
+
+/*
+Pluck returns the values of key of all records (maps) containing the key (e.g. pluck "name" $rows).
+Like sprig 'pluck', the records can also be given as separate maps (pluck "name" $map1 $map2).
+*/
+func Pluck(key string, args ...any) ([]any, error) {
+	records := args
+	if len(args) == 1 {
+		if list, ok := ToList(args[0]); ok {
+			records = list
+		}
+	}
+	result := []any{}
+	for _, record := range records {
+		if value, ok := lookupField(record, key); ok {
+			result = append(result, value)
+		}
+	}
+	return result, nil
+}
+
+/*
+UniqueBy returns the first record (map) for each distinct value of key (e.g. uniqueBy "email" $rows).
+*/
+func UniqueBy(key string, list any) ([]any, error) {
+	records, err := toRecords("uniqueBy", list)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	result := []any{}
+	for _, record := range records {
+		value := valueString(fieldValue(record, key))
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, record)
+	}
+	return result, nil
+}
+
+/*
+SumBy sums the numeric values of key of all records (maps), empty values are skipped (e.g. sumBy "amount" $rows).
+*/
+func SumBy(key string, list any) (float64, error) {
+	records, err := toRecords("sumBy", list)
+	if err != nil {
+		return 0, err
+	}
+	sum := 0.0
+	for i, record := range records {
+		value := fieldValue(record, key)
+		if value == nil || valueString(value) == "" {
+			continue
+		}
+		number, ok := toNumber(value)
+		if !ok {
+			return 0, fmt.Errorf("sumBy: value is not numeric, record=[%d], key=[%v], value=[%v]", i+1, key, value)
+		}
+		sum += number
+	}
+	return sum, nil
+}
+
+/*
+Chunk splits list into lists of given size, the last list may be shorter (e.g. chunk 3 $rows).
+*/
+func Chunk(size int, list any) ([][]any, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("chunk needs a size greater than 0, size=[%d]", size)
+	}
+	elements, ok := ToList(list)
+	if !ok {
+		return nil, fmt.Errorf("chunk needs a list, type=[%T]", list)
+	}
+	chunks := [][]any{}
+	for start := 0; start < len(elements); start += size {
+		end := start + size
+		if end > len(elements) {
+			end = len(elements)
+		}
+		chunks = append(chunks, elements[start:end:end])
+	}
+	return chunks, nil
+}
+
+/*
+toRecords converts list (e.g. []map[string]string, []map[string]any, []any) into slice of any.
+*/
+func toRecords(function string, list any) ([]any, error) {
+	if list == nil {
+		return []any{}, nil
+	}
+	records, ok := ToList(list)
+	if !ok {
+		return nil, fmt.Errorf("%s needs a list, type=[%T]", function, list)
+	}
+	return records, nil
+}
+
+/*
+fieldValue returns value of key of record (nil if not present).
+*/
+func fieldValue(record any, key string) any {
+	value, _ := lookupField(record, key)
+	return value
+}
+
+/*
+lookupField returns value of key of record (map). Nested values can be addressed with
+dotted keys (e.g. "address.city") as long as the record doesn't contain the dotted key itself.
+*/
+func lookupField(record any, key string) (any, bool) {
+	m, ok := ToMap(record)
+	if !ok {
+		return nil, false
+	}
+	if value, ok := m[key]; ok {
+		return value, true
+	}
+	head, tail, found := strings.Cut(key, ".")
+	if !found {
+		return nil, false
+	}
+	value, ok := m[head]
+	if !ok {
+		return nil, false
+	}
+	return lookupField(value, tail)
+}
+
+/*
+CompareValues compares two values numeric-aware (-1, 0, +1). Numbers and numeric strings are
+compared as numbers, times chronologically, all other values as strings. Nil is less than any other value.
+*/
+func CompareValues(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	if numberA, ok := toNumber(a); ok {
+		if numberB, ok := toNumber(b); ok {
+			switch {
+			case numberA < numberB:
+				return -1
+			case numberA > numberB:
+				return 1
+			}
+			return 0
+		}
+	}
+	if timeA, ok := a.(time.Time); ok {
+		if timeB, ok := b.(time.Time); ok {
+			switch {
+			case timeA.Before(timeB):
+				return -1
+			case timeA.After(timeB):
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(valueString(a), valueString(b))
+}
+
+/*
+toNumber converts numbers and numeric strings into float64.
+*/
+func toNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case decimal.Decimal:
+		return v.InexactFloat64(), true
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			return 0, false
+		}
+		return number, true
+	}
+	return 0, false
+}
+
+/*
+valueString returns string representation of value (empty for nil).
+*/
+func valueString(value any) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
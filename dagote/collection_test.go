@@ -0,0 +1,104 @@
+package dagote
+
+import (
+	"reflect"
+	"testing"
+)
+
+/*
+TestSortBy tests sorting of records by one or more keys (stable, numeric-aware, descending with '-').
+*/
+func TestSortBy(t *testing.T) {
+	records := []any{
+		map[string]any{"name": "ann", "dept": "b", "salary": "900"},
+		map[string]any{"name": "bob", "dept": "a", "salary": "1000"},
+		map[string]any{"name": "cid", "dept": "b", "salary": 1200},
+		map[string]any{"name": "dan", "dept": "a", "salary": "1000"},
+		map[string]any{"name": "eve"},
+	}
+	tests := []struct {
+		name    string
+		keys    string
+		list    any
+		want    []string
+		wantErr bool
+	}{
+		{"numeric strings", "salary", records, []string{"eve", "ann", "bob", "dan", "cid"}, false},
+		{"descending", "-salary", records, []string{"cid", "bob", "dan", "ann", "eve"}, false},
+		{"multiple keys", "dept, -salary", records, []string{"eve", "bob", "dan", "cid", "ann"}, false},
+		{"stable for equal keys", "dept", records, []string{"eve", "bob", "dan", "ann", "cid"}, false},
+		{"strings", "-name", records, []string{"eve", "dan", "cid", "bob", "ann"}, false},
+		{"csv records", "name", []map[string]string{{"name": "10"}, {"name": "9"}, {"name": "x"}}, []string{"9", "10", "x"}, false},
+		{"nil list", "name", nil, []string{}, false},
+		{"empty key", "name,", records, nil, true},
+		{"no list", "name", "text", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SortBy(tt.keys, tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SortBy(%q) error = %v, wantErr %v", tt.keys, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			names := []string{}
+			for _, record := range got {
+				names = append(names, valueString(fieldValue(record, "name")))
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("SortBy(%q) = %q, want %q", tt.keys, names, tt.want)
+			}
+		})
+	}
+}
+
+/*
+TestWhere tests filtering of records by comparison of key values (numeric-aware operators).
+*/
+func TestWhere(t *testing.T) {
+	records := []any{
+		map[string]any{"name": "ann", "age": "9", "active": true, "city": "Berlin"},
+		map[string]any{"name": "bob", "age": 17.0, "active": "true", "city": "Bonn"},
+		map[string]any{"name": "cid", "age": "18", "active": false},
+		map[string]any{"name": "dan", "age": 100, "address": map[string]any{"city": "Essen"}},
+	}
+	tests := []struct {
+		name    string
+		key     string
+		args    []any
+		want    []string
+		wantErr bool
+	}{
+		{"equal (default operator)", "active", []any{"true", records}, []string{"ann", "bob"}, false},
+		{"equal numeric", "age", []any{"==", 17, records}, []string{"bob"}, false},
+		{"not equal", "active", []any{"!=", true, records}, []string{"cid", "dan"}, false},
+		{"greater or equal numeric", "age", []any{">=", 18, records}, []string{"cid", "dan"}, false},
+		{"greater or equal numeric string", "age", []any{"ge", "18", records}, []string{"cid", "dan"}, false},
+		{"less than", "age", []any{"<", "10", records}, []string{"ann"}, false},
+		{"missing values don't match ordering", "city", []any{">", "A", records}, []string{"ann", "bob"}, false},
+		{"contains", "city", []any{"contains", "B", records}, []string{"ann", "bob"}, false},
+		{"nested key", "address.city", []any{"Essen", records}, []string{"dan"}, false},
+		{"no match", "name", []any{"eve", records}, []string{}, false},
+		{"unsupported operator", "age", []any{"~", 1, records}, nil, true},
+		{"missing list", "age", []any{1}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Where(tt.key, tt.args...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Where(%q) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			names := []string{}
+			for _, record := range got {
+				names = append(names, valueString(fieldValue(record, "name")))
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("Where(%q, %v) = %q, want %q", tt.key, tt.args[:len(tt.args)-1], names, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,399 @@
+package dagote
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	xml "github.com/clbanning/mxj/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ConvertTypes lists the supported output types of Convert.
+var ConvertTypes = []string{"json", "yaml", "toml", "csv", "xml", "text", "lines"}
+
+/*
+ConvertOptions controls the output of Convert.
+*/
+type ConvertOptions struct {
+	Pretty   bool       // indented output (JSON, XML)
+	SortKeys bool       // sort keys of maps (default: keep key order of JSON, YAML and CSV input)
+	Header   []string   // CSV columns and their order (default: keys of first record)
+	Root     string     // root element name of XML output (default: root of XML input or 'doc')
+	CSV      CSVOptions // dialect of CSV input
+}
+
+/*
+Convert parses data of type 'from' (see DotTypes, 'auto' detects the type by content)
+and serializes it to type 'to' (see ConvertTypes).
+TOML and XML output always have sorted keys.
+*/
+func Convert(data []byte, from, to string, opts ConvertOptions) ([]byte, error) {
+	from = strings.ToLower(from)
+	to = strings.ToLower(to)
+	if from == "" || from == "auto" {
+		from = SniffType(data)
+	}
+	if from == "csv" && len(opts.Header) > 0 {
+		// header selects columns of records
+		from = "csvmap"
+	}
+	if !contains(ConvertTypes, to) {
+		return nil, fmt.Errorf("unsupported output type, type=[%v], supported=[%v]", to, strings.Join(ConvertTypes, ", "))
+	}
+
+	var value any
+	var err error
+	root := opts.Root
+	switch {
+	case from == "xml":
+		var rootName string
+		value, rootName, err = parseXMLWithRoot(data)
+		if root == "" {
+			root = rootName
+		}
+	case !opts.SortKeys && (from == "json" || from == "yaml" || from == "csvmap"):
+		value, err = parseOrdered(data, from, opts.CSV)
+	default:
+		value, err = parseDotData(data, from, "input", opts.CSV)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var output string
+	switch to {
+	case "json":
+		indent := ""
+		if opts.Pretty {
+			indent = "  "
+		}
+		output, err = encodeJSON(value, indent)
+		if err == nil {
+			output += "\n"
+		}
+	case "yaml":
+		output, err = ToYAML(value)
+	case "toml":
+		output, err = ToTOML(plainValue(value))
+	case "csv":
+		header := opts.Header
+		if len(header) == 0 {
+			header = firstRecordKeys(value, opts.SortKeys)
+		}
+		if len(header) == 0 {
+			output, err = ToCSV(plainValue(value))
+		} else {
+			output, err = ToCSV(plainValue(value), header)
+		}
+	case "xml":
+		output, err = encodeXML(plainValue(value), root, opts.Pretty)
+	case "text":
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("unable to convert data to text, data must be text, type=[%T]", plainValue(value))
+		}
+		output = text
+	case "lines":
+		list, ok := ToList(value)
+		if !ok {
+			return nil, fmt.Errorf("unable to convert data to lines, data must be a list, type=[%T]", plainValue(value))
+		}
+		var buf strings.Builder
+		for _, element := range list {
+			buf.WriteString(valueString(element))
+			buf.WriteString("\n")
+		}
+		output = buf.String()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(output), nil
+}
+
+/*
+parseXMLWithRoot unmarshals XML data to map of any and returns the name of the root element.
+*/
+func parseXMLWithRoot(data []byte) (map[string]any, string, error) {
+	xmlRoot, err := xml.NewMapXml(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to parse XML data, error=[%w]", err)
+	}
+	rootName, err := xmlRoot.Root()
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to unmarshal XML data, error=[%w]", err)
+	}
+	m, ok := xmlRoot[rootName].(map[string]any)
+	if !ok {
+		// root element with text only
+		m = map[string]any{"#text": xmlRoot[rootName]}
+	}
+	return m, rootName, nil
+}
+
+/*
+encodeXML serializes map to XML with given root element name (compact or indented).
+*/
+func encodeXML(data any, root string, pretty bool) (string, error) {
+	m, ok := escapeXMLValues(NormalizeData(data)).(map[string]any)
+	if !ok {
+		return "", fmt.Errorf("unable to serialize data to XML, data must be a map, type=[%T]", data)
+	}
+	var roots []string
+	if root != "" {
+		roots = append(roots, root)
+	}
+	var xmlRaw []byte
+	var err error
+	if pretty {
+		xmlRaw, err = xml.Map(m).XmlIndent("", "  ", roots...)
+	} else {
+		xmlRaw, err = xml.Map(m).Xml(roots...)
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to serialize data to XML, error=[%w]", err)
+	}
+	return string(xmlRaw) + "\n", nil
+}
+
+/*
+orderedMap is a map that keeps the key order of its input (JSON, YAML, CSV).
+*/
+type orderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+/*
+set adds or replaces key (new keys are appended).
+*/
+func (m *orderedMap) set(key string, value any) {
+	if _, exists := m.values[key]; !exists {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+/*
+MarshalJSON writes the map in key order.
+*/
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		err := encoder.Encode(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1) // newline of Encode
+		buf.WriteByte(':')
+		err = encoder.Encode(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+/*
+MarshalYAML writes the map in key order.
+*/
+func (m *orderedMap) MarshalYAML() (any, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, key := range m.keys {
+		keyNode := &yaml.Node{}
+		err := keyNode.Encode(key)
+		if err != nil {
+			return nil, err
+		}
+		valueNode := &yaml.Node{}
+		err = valueNode.Encode(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, keyNode, valueNode)
+	}
+	return node, nil
+}
+
+/*
+parseOrdered parses JSON, YAML or CSV (with header) data keeping the key order.
+*/
+func parseOrdered(data []byte, from string, csvOpts CSVOptions) (any, error) {
+	switch from {
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		value, err := decodeOrderedJSON(decoder)
+		if err != nil {
+			return nil, fmt.Errorf("unable to unmarshal JSON data, error=[%w]", err)
+		}
+		if _, err := decoder.Token(); err != io.EOF {
+			return nil, errors.New("unable to unmarshal JSON data, error=[unexpected data after top-level value]")
+		}
+		return value, nil
+	case "yaml":
+		var node yaml.Node
+		err := yaml.Unmarshal(data, &node)
+		if err != nil {
+			return nil, fmt.Errorf("unable to unmarshal YAML data, error=[%w]", err)
+		}
+		if node.Kind == 0 {
+			return nil, nil
+		}
+		return decodeOrderedYAML(&node)
+	}
+
+	header, records, _, err := csvMapRecords(data, "input", csvOpts)
+	if err != nil {
+		return nil, err
+	}
+	result := []any{}
+	for _, record := range records {
+		m := &orderedMap{values: make(map[string]any)}
+		for i, column := range header {
+			if column != "" {
+				m.set(column, record[i])
+			}
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+/*
+decodeOrderedJSON decodes next JSON value (objects as orderedMap).
+*/
+func decodeOrderedJSON(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := token.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			m := &orderedMap{values: make(map[string]any)}
+			for decoder.More() {
+				keyToken, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := decodeOrderedJSON(decoder)
+				if err != nil {
+					return nil, err
+				}
+				m.set(keyToken.(string), value)
+			}
+			_, err = decoder.Token() // '}'
+			return m, err
+		case '[':
+			list := []any{}
+			for decoder.More() {
+				value, err := decodeOrderedJSON(decoder)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+			_, err = decoder.Token() // ']'
+			return list, err
+		}
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		return t.Float64()
+	}
+	return token, nil
+}
+
+/*
+decodeOrderedYAML decodes YAML node (mappings as orderedMap).
+*/
+func decodeOrderedYAML(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return decodeOrderedYAML(node.Content[0])
+	case yaml.AliasNode:
+		return decodeOrderedYAML(node.Alias)
+	case yaml.MappingNode:
+		m := &orderedMap{values: make(map[string]any)}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value, err := decodeOrderedYAML(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			m.set(node.Content[i].Value, value)
+		}
+		return m, nil
+	case yaml.SequenceNode:
+		list := []any{}
+		for _, child := range node.Content {
+			value, err := decodeOrderedYAML(child)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	}
+	var value any
+	err := node.Decode(&value)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal YAML data, line=[%d], error=[%w]", node.Line, err)
+	}
+	return value, nil
+}
+
+/*
+plainValue converts ordered maps (recursively) into maps of any.
+*/
+func plainValue(value any) any {
+	switch v := value.(type) {
+	case *orderedMap:
+		m := make(map[string]any, len(v.values))
+		for key, element := range v.values {
+			m[key] = plainValue(element)
+		}
+		return m
+	case []any:
+		list := make([]any, len(v))
+		for i, element := range v {
+			list[i] = plainValue(element)
+		}
+		return list
+	}
+	return value
+}
+
+/*
+firstRecordKeys returns the keys of the first record of a list in input order (or sorted).
+*/
+func firstRecordKeys(value any, sortKeys bool) []string {
+	list, ok := value.([]any)
+	if !ok || len(list) == 0 {
+		return nil
+	}
+	if m, ok := list[0].(*orderedMap); ok && !sortKeys {
+		return m.keys
+	}
+	if _, ok := ToMap(list[0]); !ok {
+		return nil
+	}
+	return recordKeys(list[:1])
+}
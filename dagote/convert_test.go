@@ -0,0 +1,40 @@
+package dagote
+
+import "testing"
+
+/*
+TestConvertKeyOrder tests that Convert keeps the key order of the input unless keys are sorted.
+*/
+func TestConvertKeyOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		from string
+		to   string
+		opts ConvertOptions
+		want string
+	}{
+		{"json to json", `{"z": 1, "a": {"y": 2, "b": 3}}`, "json", "json", ConvertOptions{}, `{"z":1,"a":{"y":2,"b":3}}` + "\n"},
+		{"json to json sorted", `{"z": 1, "a": {"y": 2, "b": 3}}`, "json", "json", ConvertOptions{SortKeys: true}, `{"a":{"b":3,"y":2},"z":1}` + "\n"},
+		{"json to yaml", `{"z": 1, "a": [{"k": 2, "b": 3}]}`, "json", "yaml", ConvertOptions{}, "z: 1\na:\n  - k: 2\n    b: 3\n"},
+		{"yaml to json", "z: 1\na:\n  y: 2\n  b: 3\n", "yaml", "json", ConvertOptions{}, `{"z":1,"a":{"y":2,"b":3}}` + "\n"},
+		{"yaml to json sorted", "z: 1\na:\n  y: 2\n  b: 3\n", "yaml", "json", ConvertOptions{SortKeys: true}, `{"a":{"b":3,"y":2},"z":1}` + "\n"},
+		{"csv to json", "z,a\n1,2\n", "csvmap", "json", ConvertOptions{}, `[{"z":"1","a":"2"}]` + "\n"},
+		{"json to csv", `[{"z": 1, "a": 2}, {"a": 4, "z": 3}]`, "json", "csv", ConvertOptions{}, "z,a\n1,2\n3,4\n"},
+		{"json to csv sorted", `[{"z": 1, "a": 2}]`, "json", "csv", ConvertOptions{SortKeys: true}, "a,z\n2,1\n"},
+		{"json to csv with header", `[{"z": 1, "a": 2, "m": 3}]`, "json", "csv", ConvertOptions{Header: []string{"m", "z"}}, "m,z\n3,1\n"},
+		{"csv with header selects columns", "z,a,m\n1,2,3\n", "csv", "csv", ConvertOptions{Header: []string{"m", "z"}}, "m,z\n3,1\n"},
+		{"json to toml", `{"z": 1, "a": 2}`, "json", "toml", ConvertOptions{}, "a = 2\nz = 1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Convert([]byte(tt.data), tt.from, tt.to, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Convert(%q, %v, %v) = %q, want %q", tt.data, tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,399 @@
+package dagote
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// utf8BOM is the UTF-8 byte order mark (written by e.g. Excel)
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+/*
+CSVOptions describes the dialect of CSV data (zero value: RFC 4180 with ',' as delimiter).
+*/
+type CSVOptions struct {
+	Comma          rune // field delimiter (default: ',')
+	Comment        rune // lines starting with comment character are ignored (default: none)
+	Trim           bool // trim leading and trailing white space of fields
+	Header         int  // row number of header (1-based, rows before header are skipped, default: 1)
+	KeepBOM        bool // keep UTF-8 byte order mark (default: stripped)
+	LazyQuotes     bool // allow quotes in unquoted fields and non-doubled quotes in quoted fields
+	VariableFields bool // allow variable number of fields per record
+
+	// policies for records read into maps (readCSVMap, csvmap, readCSVTyped)
+	ShortRows        string // rows with fewer fields than header: error (default), pad (empty strings)
+	LongRows         string // rows with more fields than header: error (default), ignore (extra fields dropped)
+	DuplicateHeaders string // duplicate column names: error (default), suffix (name_2, name_3, ...)
+	EmptyHeaders     string // empty column names: name (default, column_N), error, skip (column ignored)
+}
+
+// CSVOptionNames lists the supported keys of CSV option maps (readCSVWith, -dotopt).
+var CSVOptionNames = []string{"comma", "comment", "trim", "header", "stripBOM", "lazyQuotes", "variableFields",
+	"shortRows", "longRows", "duplicateHeaders", "emptyHeaders"}
+
+// csvPolicies lists the allowed values of the CSV map policies (first value is default).
+var csvPolicies = map[string][]string{
+	"shortRows":        {"error", "pad"},
+	"longRows":         {"error", "ignore"},
+	"duplicateHeaders": {"error", "suffix"},
+	"emptyHeaders":     {"name", "error", "skip"},
+}
+
+/*
+ParseCSVOptions converts option map (e.g. dict "comma" ";" "comment" "#" "trim" true "header" 2) into CSV options.
+Values may be given as strings (e.g. from command line: "true", "2", "tab").
+*/
+func ParseCSVOptions(options map[string]any) (CSVOptions, error) {
+	var opts CSVOptions
+	for _, key := range sortedOptionKeys(options) {
+		value := options[key]
+		var err error
+		switch key {
+		case "comma":
+			opts.Comma, err = optionRune(value)
+		case "comment":
+			opts.Comment, err = optionRune(value)
+		case "trim":
+			opts.Trim, err = optionBool(value)
+		case "header":
+			opts.Header, err = optionInt(value)
+			if err == nil && opts.Header < 0 {
+				err = errors.New("header row must not be negative")
+			}
+		case "stripBOM":
+			var strip bool
+			strip, err = optionBool(value)
+			opts.KeepBOM = !strip
+		case "lazyQuotes":
+			opts.LazyQuotes, err = optionBool(value)
+		case "variableFields":
+			opts.VariableFields, err = optionBool(value)
+		case "shortRows":
+			opts.ShortRows, err = optionPolicy(key, value)
+		case "longRows":
+			opts.LongRows, err = optionPolicy(key, value)
+		case "duplicateHeaders":
+			opts.DuplicateHeaders, err = optionPolicy(key, value)
+		case "emptyHeaders":
+			opts.EmptyHeaders, err = optionPolicy(key, value)
+		default:
+			return opts, fmt.Errorf("unsupported CSV option, option=[%v], supported=[%v]", key, strings.Join(CSVOptionNames, ", "))
+		}
+		if err != nil {
+			return opts, fmt.Errorf("invalid value of CSV option, option=[%v], value=[%v], error=[%w]", key, value, err)
+		}
+	}
+	if opts.Comma != 0 && opts.Comma == opts.Comment {
+		return opts, fmt.Errorf("CSV options 'comma' and 'comment' must differ, value=[%q]", opts.Comma)
+	}
+	return opts, nil
+}
+
+/*
+ReadCSVWith reads all records of csv file with given dialect into two-dimensional slice of strings
+(e.g. readCSVWith (dict "comma" ";" "comment" "#") "file.csv"). Rows before the header row are skipped.
+*/
+func (e *Engine) ReadCSVWith(options map[string]any, filename string) ([][]string, error) {
+	if filename == "" {
+		return nil, errors.New("readCSVWith needs a filename")
+	}
+	opts, err := ParseCSVOptions(options)
+	if err != nil {
+		return nil, fmt.Errorf("readCSVWith: %w", err)
+	}
+	err = e.checkRead(filename)
+	if err != nil {
+		return nil, err
+	}
+	e.recordRead(filename)
+	kind := fmt.Sprintf("csv%+v", opts)
+	if cached, ok := e.lookupCache(kind, filename); ok {
+		return cached.([][]string), nil
+	}
+	csvRaw, err := e.readFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CSV file, file=[%v], error=[%w]", filename, err)
+	}
+	records, err := parseCSVWith(csvRaw, filename, opts)
+	if err != nil {
+		return nil, err
+	}
+	e.storeCache(kind, filename, records)
+	return records, nil
+}
+
+/*
+ReadCSVMapWith reads all records of csv file with given dialect into slice of maps
+(e.g. readCSVMapWith (dict "comma" "\t" "header" 3) "file.tsv").
+*/
+func (e *Engine) ReadCSVMapWith(options map[string]any, filename string) ([]map[string]string, error) {
+	if filename == "" {
+		return nil, errors.New("readCSVMapWith needs a filename")
+	}
+	opts, err := ParseCSVOptions(options)
+	if err != nil {
+		return nil, fmt.Errorf("readCSVMapWith: %w", err)
+	}
+	err = e.checkRead(filename)
+	if err != nil {
+		return nil, err
+	}
+	e.recordRead(filename)
+	kind := fmt.Sprintf("csvmap%+v", opts)
+	if cached, ok := e.lookupCache(kind, filename); ok {
+		return cached.([]map[string]string), nil
+	}
+	csvRaw, err := e.readFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CSV file, file=[%v], error=[%w]", filename, err)
+	}
+	records, err := parseCSVMapWith(csvRaw, filename, opts)
+	if err != nil {
+		return nil, err
+	}
+	e.storeCache(kind, filename, records)
+	return records, nil
+}
+
+/*
+parseCSVWith reads all records of csv data with given dialect into two-dimensional slice of strings.
+*/
+func parseCSVWith(csvRaw []byte, filename string, opts CSVOptions) ([][]string, error) {
+	records, _, err := csvRecords(csvRaw, filename, opts)
+	return records, err
+}
+
+/*
+csvRecords reads all records of csv data with given dialect and returns the line number of each record.
+*/
+func csvRecords(csvRaw []byte, filename string, opts CSVOptions) ([][]string, []int, error) {
+	if !opts.KeepBOM {
+		csvRaw = bytes.TrimPrefix(csvRaw, utf8BOM)
+	}
+	reader := csv.NewReader(bytes.NewReader(csvRaw))
+	if opts.Comma != 0 {
+		reader.Comma = opts.Comma
+	}
+	reader.Comment = opts.Comment
+	reader.TrimLeadingSpace = opts.Trim
+	reader.LazyQuotes = opts.LazyQuotes
+	// number of fields is checked below (rows before header row may differ)
+	reader.FieldsPerRecord = -1
+
+	var records [][]string
+	var lines []int
+	fields := 0
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, nil, fmt.Errorf("unable to read all CSV records, file=[%v], error=[%w]", filename, err)
+		}
+		if row < opts.Header {
+			// rows before header row
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		if !opts.VariableFields {
+			if fields == 0 {
+				fields = len(record)
+			} else if len(record) != fields {
+				return nil, nil, fmt.Errorf("wrong number of fields in CSV record, file=[%v], line=[%d], expected=[%d], found=[%d]", filename, line, fields, len(record))
+			}
+		}
+		if opts.Trim {
+			for i := range record {
+				record[i] = strings.TrimSpace(record[i])
+			}
+		}
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	if opts.Header > 1 && len(records) == 0 {
+		return nil, nil, fmt.Errorf("header row not found, file=[%v], header=[%d]", filename, opts.Header)
+	}
+	return records, lines, nil
+}
+
+/*
+sortedOptionKeys returns the keys of option map in sorted order (deterministic error messages).
+*/
+func sortedOptionKeys(options map[string]any) []string {
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/*
+optionRune converts option value into single character ('tab', '\t' for tabulator).
+*/
+func optionRune(value any) (rune, error) {
+	text := fmt.Sprint(value)
+	switch strings.ToLower(text) {
+	case "tab", `\t`:
+		return '\t', nil
+	case "semicolon":
+		return ';', nil
+	case "comma":
+		return ',', nil
+	case "pipe":
+		return '|', nil
+	case "space":
+		return ' ', nil
+	}
+	if utf8.RuneCountInString(text) != 1 {
+		return 0, errors.New("value must be a single character")
+	}
+	r, _ := utf8.DecodeRuneInString(text)
+	if r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return 0, errors.New("character not allowed")
+	}
+	return r, nil
+}
+
+/*
+optionPolicy checks the value of a CSV map policy.
+*/
+func optionPolicy(key string, value any) (string, error) {
+	policy := strings.ToLower(strings.TrimSpace(fmt.Sprint(value)))
+	if !contains(csvPolicies[key], policy) {
+		return "", fmt.Errorf("unsupported policy, supported=[%v]", strings.Join(csvPolicies[key], ", "))
+	}
+	return policy, nil
+}
+
+/*
+optionBool converts option value (bool or string) into bool.
+*/
+func optionBool(value any) (bool, error) {
+	if b, ok := value.(bool); ok {
+		return b, nil
+	}
+	return strconv.ParseBool(fmt.Sprint(value))
+}
+
+/*
+optionInt converts option value (number or string) into int.
+*/
+func optionInt(value any) (int, error) {
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v != float64(int(v)) {
+			return 0, errors.New("value must be an integer")
+		}
+		return int(v), nil
+	}
+	return strconv.Atoi(strings.TrimSpace(fmt.Sprint(value)))
+}
+
+/*
+csvMapRecords reads all records of csv data for conversion into maps. It returns the column names of the
+header (empty name: column is skipped), the data records adjusted to the header length and their line numbers.
+*/
+func csvMapRecords(csvRaw []byte, filename string, opts CSVOptions) ([]string, [][]string, []int, error) {
+	// number of fields is checked against header according to row policies
+	readOpts := opts
+	readOpts.VariableFields = true
+	records, lines, err := csvRecords(csvRaw, filename, readOpts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil, nil, nil
+	}
+	header, err := csvHeader(records[0], lines[0], filename, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	records, lines = records[1:], lines[1:]
+	for i, record := range records {
+		switch {
+		case len(record) < len(header):
+			if opts.ShortRows != "pad" {
+				return nil, nil, nil, fmt.Errorf("CSV record has fewer fields than header (see option 'shortRows'), file=[%v], line=[%d], header=[%d], found=[%d]",
+					filename, lines[i], len(header), len(record))
+			}
+			padded := make([]string, len(header))
+			copy(padded, record)
+			records[i] = padded
+		case len(record) > len(header):
+			if opts.LongRows != "ignore" {
+				return nil, nil, nil, fmt.Errorf("CSV record has more fields than header (see option 'longRows'), file=[%v], line=[%d], header=[%d], found=[%d]",
+					filename, lines[i], len(header), len(record))
+			}
+			records[i] = record[:len(header)]
+		}
+	}
+	return header, records, lines, nil
+}
+
+/*
+csvHeader determines the column names of header record according to the header policies.
+*/
+func csvHeader(record []string, line int, filename string, opts CSVOptions) ([]string, error) {
+	header := make([]string, len(record))
+	used := make(map[string]bool, len(record))
+	for i, name := range record {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			used[name] = true
+		}
+		header[i] = name
+	}
+
+	seen := make(map[string]bool, len(record))
+	for i, name := range header {
+		if name == "" {
+			switch opts.EmptyHeaders {
+			case "error":
+				return nil, fmt.Errorf("empty column name in CSV header (see option 'emptyHeaders'), file=[%v], line=[%d], column=[%d]", filename, line, i+1)
+			case "skip":
+				continue
+			}
+			name = uniqueColumnName(fmt.Sprintf("column_%d", i+1), used)
+			used[name] = true
+			header[i] = name
+		}
+		if seen[name] {
+			if opts.DuplicateHeaders != "suffix" {
+				return nil, fmt.Errorf("duplicate column name in CSV header (see option 'duplicateHeaders'), file=[%v], line=[%d], column=[%d], name=[%v]", filename, line, i+1, name)
+			}
+			name = uniqueColumnName(name, used)
+			used[name] = true
+			header[i] = name
+		}
+		seen[name] = true
+	}
+	return header, nil
+}
+
+/*
+uniqueColumnName returns name or, if already used, name with the first free suffix (name_2, name_3, ...).
+*/
+func uniqueColumnName(name string, used map[string]bool) string {
+	if !used[name] {
+		return name
+	}
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s_%d", name, n)
+		if !used[candidate] {
+			return candidate
+		}
+	}
+}
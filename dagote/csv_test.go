@@ -0,0 +1,148 @@
+package dagote
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/*
+TestParseCSVOptions tests the conversion of option maps into CSV options.
+*/
+func TestParseCSVOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options map[string]any
+		want    CSVOptions
+		wantErr bool
+	}{
+		{"empty", map[string]any{}, CSVOptions{}, false},
+		{"comma character", map[string]any{"comma": ";"}, CSVOptions{Comma: ';'}, false},
+		{"comma name", map[string]any{"comma": "tab"}, CSVOptions{Comma: '\t'}, false},
+		{"comma escape", map[string]any{"comma": `\t`}, CSVOptions{Comma: '\t'}, false},
+		{"comment", map[string]any{"comment": "#"}, CSVOptions{Comment: '#'}, false},
+		{"bool values", map[string]any{"trim": true, "lazyQuotes": "true", "variableFields": "1"},
+			CSVOptions{Trim: true, LazyQuotes: true, VariableFields: true}, false},
+		{"header number", map[string]any{"header": 3}, CSVOptions{Header: 3}, false},
+		{"header string", map[string]any{"header": "2"}, CSVOptions{Header: 2}, false},
+		{"header float", map[string]any{"header": 2.0}, CSVOptions{Header: 2}, false},
+		{"strip bom", map[string]any{"stripBOM": true}, CSVOptions{}, false},
+		{"keep bom", map[string]any{"stripBOM": "false"}, CSVOptions{KeepBOM: true}, false},
+		{"policies", map[string]any{"shortRows": "PAD", "longRows": "ignore", "duplicateHeaders": "suffix", "emptyHeaders": "skip"},
+			CSVOptions{ShortRows: "pad", LongRows: "ignore", DuplicateHeaders: "suffix", EmptyHeaders: "skip"}, false},
+		{"unsupported option", map[string]any{"bom": false}, CSVOptions{}, true},
+		{"comma too long", map[string]any{"comma": ";;"}, CSVOptions{}, true},
+		{"comma quote", map[string]any{"comma": `"`}, CSVOptions{}, true},
+		{"comma equals comment", map[string]any{"comma": "#", "comment": "#"}, CSVOptions{}, true},
+		{"invalid bool", map[string]any{"trim": "maybe"}, CSVOptions{}, true},
+		{"negative header", map[string]any{"header": -1}, CSVOptions{}, true},
+		{"fractional header", map[string]any{"header": 1.5}, CSVOptions{}, true},
+		{"unsupported policy", map[string]any{"shortRows": "ignore"}, CSVOptions{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCSVOptions(tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCSVOptions(%v) error = %v, want error %v", tt.options, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseCSVOptions(%v) = %+v, want %+v", tt.options, got, tt.want)
+			}
+		})
+	}
+}
+
+/*
+TestParseCSVWith tests reading CSV data with dialect options.
+*/
+func TestParseCSVWith(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		opts    CSVOptions
+		want    [][]string
+		wantErr bool
+	}{
+		{"default", "a,b\n1,2\n", CSVOptions{}, [][]string{{"a", "b"}, {"1", "2"}}, false},
+		{"semicolon", "a;b\n1;2\n", CSVOptions{Comma: ';'}, [][]string{{"a", "b"}, {"1", "2"}}, false},
+		{"tab", "a\tb\n1\t2\n", CSVOptions{Comma: '\t'}, [][]string{{"a", "b"}, {"1", "2"}}, false},
+		{"comment", "# exported\na,b\n1,2\n", CSVOptions{Comment: '#'}, [][]string{{"a", "b"}, {"1", "2"}}, false},
+		{"trim", " a , b \n 1 , 2 \n", CSVOptions{Trim: true}, [][]string{{"a", "b"}, {"1", "2"}}, false},
+		{"no trim", " a,b \n", CSVOptions{}, [][]string{{" a", "b "}}, false},
+		{"bom stripped", "\xef\xbb\xbfa,b\n", CSVOptions{}, [][]string{{"a", "b"}}, false},
+		{"bom kept", "\xef\xbb\xbfa,b\n", CSVOptions{KeepBOM: true}, [][]string{{"\ufeffa", "b"}}, false},
+		{"header row", "Report\nexported today,x,y\na,b\n1,2\n", CSVOptions{Header: 3}, [][]string{{"a", "b"}, {"1", "2"}}, false},
+		{"header row not found", "a,b\n", CSVOptions{Header: 3}, nil, true},
+		{"lazy quotes", "a,b\"c\n", CSVOptions{LazyQuotes: true}, [][]string{{"a", "b\"c"}}, false},
+		{"bare quote", "a,b\"c\n", CSVOptions{}, nil, true},
+		{"variable fields", "a,b\n1\n", CSVOptions{VariableFields: true}, [][]string{{"a", "b"}, {"1"}}, false},
+		{"wrong number of fields", "a,b\n1\n", CSVOptions{}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCSVWith([]byte(tt.data), "test.csv", tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCSVWith(%q) error = %v, want error %v", tt.data, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCSVWith(%q) = %q, want %q", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+/*
+TestParseCSVMapWith tests the row and header policies of CSV data read into maps.
+*/
+func TestParseCSVMapWith(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		opts    CSVOptions
+		want    []map[string]string
+		wantErr bool
+	}{
+		{"default", "a,b\n1,2\n", CSVOptions{}, []map[string]string{{"a": "1", "b": "2"}}, false},
+		{"header only", "a,b\n", CSVOptions{}, []map[string]string{}, false},
+		{"empty", "", CSVOptions{}, []map[string]string{}, false},
+		{"short row error", "a,b\n1\n", CSVOptions{}, nil, true},
+		{"short row pad", "a,b\n1\n", CSVOptions{ShortRows: "pad"}, []map[string]string{{"a": "1", "b": ""}}, false},
+		{"long row error", "a,b\n1,2,3\n", CSVOptions{}, nil, true},
+		{"long row ignore", "a,b\n1,2,3\n", CSVOptions{LongRows: "ignore"}, []map[string]string{{"a": "1", "b": "2"}}, false},
+		{"duplicate header error", "a,a\n1,2\n", CSVOptions{}, nil, true},
+		{"duplicate header suffix", "a,a,a\n1,2,3\n", CSVOptions{DuplicateHeaders: "suffix"},
+			[]map[string]string{{"a": "1", "a_2": "2", "a_3": "3"}}, false},
+		{"duplicate header suffix taken", "a,a,a_2\n1,2,3\n", CSVOptions{DuplicateHeaders: "suffix"},
+			[]map[string]string{{"a": "1", "a_3": "2", "a_2": "3"}}, false},
+		{"empty header name", "a,,c\n1,2,3\n", CSVOptions{}, []map[string]string{{"a": "1", "column_2": "2", "c": "3"}}, false},
+		{"empty header name taken", "column_2,\n1,2\n", CSVOptions{}, []map[string]string{{"column_2": "1", "column_2_2": "2"}}, false},
+		{"empty header error", "a,,c\n1,2,3\n", CSVOptions{EmptyHeaders: "error"}, nil, true},
+		{"empty header skip", "a,,c\n1,2,3\n", CSVOptions{EmptyHeaders: "skip"}, []map[string]string{{"a": "1", "c": "3"}}, false},
+		{"header row with policies", "title\na,b\n1\n", CSVOptions{Header: 2, ShortRows: "pad"}, []map[string]string{{"a": "1", "b": ""}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCSVMapWith([]byte(tt.data), "test.csv", tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCSVMapWith(%q) error = %v, want error %v", tt.data, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCSVMapWith(%q) = %v, want %v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+/*
+TestCSVMapErrorLine tests that errors of CSV map policies report the line of the record.
+*/
+func TestCSVMapErrorLine(t *testing.T) {
+	data := "# comment\na,b\n1,2\n\n3\n"
+	_, err := parseCSVMapWith([]byte(data), "test.csv", CSVOptions{Comment: '#'})
+	if err == nil {
+		t.Fatal("expected error for short row")
+	}
+	if want := "file=[test.csv], line=[5]"; !strings.Contains(err.Error(), want) {
+		t.Errorf("error = %v, want %v", err, want)
+	}
+}
@@ -0,0 +1,219 @@
+package dagote
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CSVColumnTypes lists the supported column types of CSV schemas ('date' accepts a layout: 'date:02.01.2006').
+var CSVColumnTypes = []string{"string", "int", "float", "bool", "date", "decimal"}
+
+// csvDateLayouts are the default layouts of column type 'date'
+var csvDateLayouts = []string{"2006-01-02", time.RFC3339}
+
+/*
+csvColumn describes the type of one CSV column.
+*/
+type csvColumn struct {
+	name     string
+	kind     string
+	layout   string // layout of type 'date' (empty: default layouts)
+	typeName string // type as given in schema
+}
+
+/*
+ReadCSVTyped reads all records of csv file into slice of maps with values converted according to schema
+(e.g. readCSVTyped (dict "qty" "int" "price" "decimal" "sold" "date") "file.csv").
+The schema is a map (column name -> type) or the name of a schema file (JSON, YAML, TOML).
+Columns not named in schema are strings, empty cells of typed columns are nil.
+Optional CSV dialect options are given as third argument (see readCSVWith).
+*/
+func (e *Engine) ReadCSVTyped(schema any, filename string, options ...map[string]any) ([]map[string]any, error) {
+	if filename == "" {
+		return nil, errors.New("readCSVTyped needs a filename")
+	}
+	if len(options) > 1 {
+		return nil, errors.New("readCSVTyped accepts only one options map")
+	}
+	var opts CSVOptions
+	var err error
+	if len(options) == 1 {
+		opts, err = ParseCSVOptions(options[0])
+		if err != nil {
+			return nil, fmt.Errorf("readCSVTyped: %w", err)
+		}
+	}
+	columnTypes, err := e.csvSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	err = e.checkRead(filename)
+	if err != nil {
+		return nil, err
+	}
+	e.recordRead(filename)
+	kind := fmt.Sprintf("csvtyped%v%+v", columnTypes, opts)
+	if cached, ok := e.lookupCache(kind, filename); ok {
+		return cached.([]map[string]any), nil
+	}
+	csvRaw, err := e.readFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CSV file, file=[%v], error=[%w]", filename, err)
+	}
+	records, err := parseCSVTyped(csvRaw, filename, columnTypes, opts)
+	if err != nil {
+		return nil, err
+	}
+	e.storeCache(kind, filename, records)
+	return records, nil
+}
+
+/*
+csvSchema returns the column types of schema given as map or as name of schema file.
+*/
+func (e *Engine) csvSchema(schema any) (map[string]string, error) {
+	if filename, ok := schema.(string); ok {
+		if filename == "" {
+			return nil, errors.New("readCSVTyped needs a schema (map or filename)")
+		}
+		err := e.checkRead(filename)
+		if err != nil {
+			return nil, err
+		}
+		e.recordRead(filename)
+		data, err := e.readFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CSV schema file, file=[%v], error=[%w]", filename, err)
+		}
+		dottype := DetectTypeByExtension(filename)
+		if dottype != "json" && dottype != "yaml" && dottype != "toml" {
+			return nil, fmt.Errorf("unsupported type of CSV schema file (json, yaml, toml), file=[%v]", filename)
+		}
+		schema, err = parseDotData(data, dottype, filename, CSVOptions{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	m, ok := ToMap(schema)
+	if !ok {
+		return nil, fmt.Errorf("CSV schema must be a map (column -> type), type=[%T]", schema)
+	}
+	columnTypes := make(map[string]string, len(m))
+	for column, value := range m {
+		typeName, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("type of CSV column must be a string, column=[%v], type=[%T]", column, value)
+		}
+		columnTypes[column] = typeName
+	}
+	return columnTypes, nil
+}
+
+/*
+parseCSVTyped reads all records of csv data into slice of maps with values converted according to column types.
+*/
+func parseCSVTyped(csvRaw []byte, filename string, columnTypes map[string]string, opts CSVOptions) ([]map[string]any, error) {
+	header, records, lines, err := csvMapRecords(csvRaw, filename, opts)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := csvColumns(header, columnTypes, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []map[string]any{}
+	for r, record := range records {
+		row := make(map[string]any, len(columns))
+		for i, field := range record {
+			if columns[i].name == "" {
+				continue
+			}
+			value, err := columns[i].convert(field)
+			if err != nil {
+				return nil, fmt.Errorf("unable to convert CSV value, file=[%v], line=[%d], row=[%d], column=[%v], type=[%v], value=[%v], error=[%w]",
+					filename, lines[r], r+1, columns[i].name, columns[i].typeName, field, err)
+			}
+			row[columns[i].name] = value
+		}
+		result = append(result, row)
+	}
+	return result, nil
+}
+
+/*
+csvColumns determines the type of each header column (all schema columns must exist in header).
+*/
+func csvColumns(header []string, columnTypes map[string]string, filename string) ([]csvColumn, error) {
+	columns := make([]csvColumn, len(header))
+	found := make(map[string]bool, len(columnTypes))
+	for i, name := range header {
+		if name == "" {
+			// skipped column
+			continue
+		}
+		typeName, ok := columnTypes[name]
+		if !ok {
+			typeName = "string"
+		}
+		found[name] = true
+		kind, layout, _ := strings.Cut(typeName, ":")
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if !contains(CSVColumnTypes, kind) || (layout != "" && kind != "date") {
+			return nil, fmt.Errorf("unsupported type of CSV column, file=[%v], column=[%v], type=[%v], supported=[%v]", filename, name, typeName, strings.Join(CSVColumnTypes, ", "))
+		}
+		columns[i] = csvColumn{name: name, kind: kind, layout: layout, typeName: typeName}
+	}
+
+	var missing []string
+	for name := range columnTypes {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("CSV schema columns not found in header, file=[%v], columns=[%v]", filename, strings.Join(missing, ", "))
+	}
+	return columns, nil
+}
+
+/*
+convert converts field into value of column type (nil for empty fields of typed columns).
+*/
+func (c csvColumn) convert(field string) (any, error) {
+	if c.kind == "string" {
+		return field, nil
+	}
+	field = strings.TrimSpace(field)
+	if field == "" {
+		return nil, nil
+	}
+	switch c.kind {
+	case "int":
+		return strconv.ParseInt(field, 10, 64)
+	case "float":
+		return strconv.ParseFloat(field, 64)
+	case "bool":
+		return strconv.ParseBool(field)
+	case "decimal":
+		return decimal.NewFromString(field)
+	}
+
+	// date
+	if c.layout != "" {
+		return time.Parse(c.layout, field)
+	}
+	for _, layout := range csvDateLayouts {
+		if t, err := time.Parse(layout, field); err == nil {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("date does not match layouts [%v]", strings.Join(csvDateLayouts, ", "))
+}
@@ -0,0 +1,72 @@
+package dagote
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+/*
+TestParseCSVTyped tests the conversion of CSV values according to column types.
+*/
+func TestParseCSVTyped(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name    string
+		data    string
+		schema  map[string]string
+		want    []map[string]any
+		wantErr bool
+	}{
+		{"string default", "a,b\nx, y \n", map[string]string{}, []map[string]any{{"a": "x", "b": " y "}}, false},
+		{"int", "n\n42\n-7\n", map[string]string{"n": "int"}, []map[string]any{{"n": int64(42)}, {"n": int64(-7)}}, false},
+		{"int with spaces", "n\n 42 \n", map[string]string{"n": "int"}, []map[string]any{{"n": int64(42)}}, false},
+		{"float", "f\n1.5\n", map[string]string{"f": "float"}, []map[string]any{{"f": 1.5}}, false},
+		{"bool", "b\ntrue\n0\n", map[string]string{"b": "bool"}, []map[string]any{{"b": true}, {"b": false}}, false},
+		{"decimal", "d\n19.99\n", map[string]string{"d": "decimal"}, []map[string]any{{"d": decimal.RequireFromString("19.99")}}, false},
+		{"date default layout", "d\n2024-03-01\n", map[string]string{"d": "date"}, []map[string]any{{"d": date(2024, 3, 1)}}, false},
+		{"date rfc3339", "d\n2024-03-01T00:00:00Z\n", map[string]string{"d": "date"}, []map[string]any{{"d": date(2024, 3, 1)}}, false},
+		{"date custom layout", "d\n01.03.2024\n", map[string]string{"d": "date:02.01.2006"}, []map[string]any{{"d": date(2024, 3, 1)}}, false},
+		{"type case insensitive", "n\n1\n", map[string]string{"n": "INT"}, []map[string]any{{"n": int64(1)}}, false},
+		{"empty typed cell", "n,s\n,\n", map[string]string{"n": "int"}, []map[string]any{{"n": nil, "s": ""}}, false},
+		{"invalid int", "n\nabc\n", map[string]string{"n": "int"}, nil, true},
+		{"invalid float", "f\n1,5\n", map[string]string{"f": "float"}, nil, true},
+		{"invalid bool", "b\nyes\n", map[string]string{"b": "bool"}, nil, true},
+		{"invalid decimal", "d\n1.2.3\n", map[string]string{"d": "decimal"}, nil, true},
+		{"invalid date", "d\n01.03.2024\n", map[string]string{"d": "date"}, nil, true},
+		{"unsupported type", "n\n1\n", map[string]string{"n": "number"}, nil, true},
+		{"layout of non date type", "n\n1\n", map[string]string{"n": "int:x"}, nil, true},
+		{"schema column missing", "a\n1\n", map[string]string{"b": "int"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCSVTyped([]byte(tt.data), "test.csv", tt.schema, CSVOptions{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCSVTyped(%q) error = %v, want error %v", tt.data, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCSVTyped(%q) = %v, want %v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+/*
+TestParseCSVTypedWithOptions tests typed columns combined with CSV dialect and map policies.
+*/
+func TestParseCSVTypedWithOptions(t *testing.T) {
+	data := "id;;qty\n1;x;5\n2;y\n"
+	opts := CSVOptions{Comma: ';', EmptyHeaders: "skip", ShortRows: "pad"}
+	want := []map[string]any{{"id": int64(1), "qty": int64(5)}, {"id": int64(2), "qty": nil}}
+	got, err := parseCSVTyped([]byte(data), "test.csv", map[string]string{"id": "int", "qty": "int"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCSVTyped(%q) = %v, want %v", data, got, want)
+	}
+}
@@ -0,0 +1,235 @@
+package dagote
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+)
+
+// names of the functions of the markers recording the template file being evaluated (see markTemplates)
+const (
+	enterTemplateFunc = "_dagote_enter_template"
+	leaveTemplateFunc = "_dagote_leave_template"
+)
+
+// DataPathModes lists the modes for resolving relative paths of data functions (readXXX, fileXXX).
+var DataPathModes = []string{"cwd", "start", "template", "datadir"}
+
+/*
+WithDataPath sets the base path for relative paths of data functions (readXXX, fileXXX) within templates:
+cwd (current working directory or root of file system), start (directory of start template),
+template (directory of the template file defining the template which calls the data function),
+datadir (directory dir).
+*/
+func WithDataPath(mode, dir string) Option {
+	return func(e *Engine) error {
+		mode = strings.ToLower(mode)
+		if !contains(DataPathModes, mode) {
+			return fmt.Errorf("data path mode not supported, mode=[%v]", mode)
+		}
+		if mode == "datadir" && dir == "" {
+			return fmt.Errorf("data path mode 'datadir' needs a directory")
+		}
+		e.dataPath = mode
+		e.dataDir = dir
+		return nil
+	}
+}
+
+/*
+baseDir returns the base directory for relative paths of data functions not bound to a template directory.
+*/
+func (e *Engine) baseDir() string {
+	switch e.dataPath {
+	case "datadir":
+		return e.dataDir
+	case "start", "template":
+		// templates without directory (e.g. from stdin) fall back to the directory of the start template
+		return e.startDir
+	}
+	return ""
+}
+
+/*
+resolvePath resolves relative file name against base directory.
+*/
+func (e *Engine) resolvePath(base, filename string) string {
+	if base == "" || filename == "" {
+		return filename
+	}
+	if e.fsys != nil {
+		return path.Join(filepath.ToSlash(base), filepath.ToSlash(filename))
+	}
+	if filepath.IsAbs(filename) {
+		return filename
+	}
+	return filepath.Join(base, filename)
+}
+
+/*
+loaderFuncs returns the data functions (groups data and file) resolving relative paths against the data base directory.
+*/
+func (e *Engine) loaderFuncs() map[string]map[string]any {
+	return map[string]map[string]any{
+		"data": {
+			"readJSON": func(filename string) (any, error) {
+				return e.ReadJSON(e.resolvePath(e.dataBase(), filename))
+			},
+			"readJSONMap": func(filename string) (map[string]any, error) {
+				return e.ReadJSONMap(e.resolvePath(e.dataBase(), filename))
+			},
+			"readJSONArray": func(filename string) ([]any, error) {
+				return e.ReadJSONArray(e.resolvePath(e.dataBase(), filename))
+			},
+			"readYAML": func(filename string) (any, error) {
+				return e.ReadYAML(e.resolvePath(e.dataBase(), filename))
+			},
+			"readCSV": func(filename string) ([][]string, error) {
+				return e.ReadCSV(e.resolvePath(e.dataBase(), filename))
+			},
+			"readCSVMap": func(filename string) ([]map[string]string, error) {
+				return e.ReadCSVMap(e.resolvePath(e.dataBase(), filename))
+			},
+			"readCSVWith": func(options map[string]any, filename string) ([][]string, error) {
+				return e.ReadCSVWith(options, e.resolvePath(e.dataBase(), filename))
+			},
+			"readCSVMapWith": func(options map[string]any, filename string) ([]map[string]string, error) {
+				return e.ReadCSVMapWith(options, e.resolvePath(e.dataBase(), filename))
+			},
+			"readCSVTyped": func(schema any, filename string, options ...map[string]any) ([]map[string]any, error) {
+				if schemaFile, ok := schema.(string); ok && schemaFile != "" {
+					schema = e.resolvePath(e.dataBase(), schemaFile)
+				}
+				return e.ReadCSVTyped(schema, e.resolvePath(e.dataBase(), filename), options...)
+			},
+			"readText": func(filename string) (string, error) {
+				return e.ReadText(e.resolvePath(e.dataBase(), filename))
+			},
+			"readLines": func(filename string) ([]string, error) {
+				return e.ReadLines(e.resolvePath(e.dataBase(), filename))
+			},
+			"readXML": func(filename string) (map[string]any, error) {
+				return e.ReadXML(e.resolvePath(e.dataBase(), filename))
+			},
+			"readYAMLAll": func(filename string) ([]any, error) {
+				return e.ReadYAMLAll(e.resolvePath(e.dataBase(), filename))
+			},
+			"readNDJSON": func(filename string) ([]any, error) {
+				return e.ReadNDJSON(e.resolvePath(e.dataBase(), filename))
+			},
+			"readTOML": func(filename string) (any, error) {
+				return e.ReadTOML(e.resolvePath(e.dataBase(), filename))
+			},
+			"readXMLDoc": func(filename string, options ...map[string]any) (*XMLNode, error) {
+				return e.ReadXMLDoc(e.resolvePath(e.dataBase(), filename), options...)
+			},
+		},
+		"file": {
+			"fileExists": func(filename string) (bool, error) {
+				return e.FileExists(e.resolvePath(e.dataBase(), filename))
+			},
+			"fileStat": func(filename string) (os.FileInfo, error) {
+				return e.FileStat(e.resolvePath(e.dataBase(), filename))
+			},
+			"fileRead": func(filename string) ([]byte, error) {
+				return e.FileRead(e.resolvePath(e.dataBase(), filename))
+			},
+		},
+	}
+}
+
+/*
+dataBase returns the base directory for relative paths of data functions. For mode template this is the
+directory of the template file defining the template being evaluated (templates without file fall back
+to the base directory).
+*/
+func (e *Engine) dataBase() string {
+	if e.dataPath == "template" {
+		if parseName, ok := e.running().template(); ok {
+			if dir := e.templateDirs[parseName]; dir != "" {
+				return dir
+			}
+		}
+	}
+	return e.baseDir()
+}
+
+/*
+markTemplates adds markers to all templates parsed from file (parse name), which record the template file
+being evaluated (see dataBase): {{if enter "parse name"}}{{end}} at the beginning and {{if leave}}{{end}}
+at the end. The markers produce no output (also not in html templates, conditions are not escaped).
+*/
+func (e *Engine) markTemplates(parseName string) error {
+	text := fmt.Sprintf("{{if %s %q}}{{end}}{{if %s}}{{end}}", enterTemplateFunc, parseName, leaveTemplateFunc)
+	// function check only, the functions are bound by the executor
+	placeholder := func() bool { return false }
+	trees, err := parse.Parse("marker", text, "{{", "}}", map[string]any{enterTemplateFunc: placeholder, leaveTemplateFunc: placeholder})
+	if err != nil {
+		return fmt.Errorf("unable to parse template markers, name=[%v], error=[%w]", parseName, err)
+	}
+	markers := trees["marker"].Root.Nodes
+	for _, tree := range e.trees() {
+		if tree.ParseName != parseName || parse.IsEmptyTree(tree.Root) || isMarked(tree) {
+			continue
+		}
+		nodes := make([]parse.Node, 0, len(tree.Root.Nodes)+2)
+		nodes = append(nodes, markers[0].Copy())
+		nodes = append(nodes, tree.Root.Nodes...)
+		nodes = append(nodes, markers[1].Copy())
+		tree.Root.Nodes = nodes
+	}
+	return nil
+}
+
+/*
+trees returns the parse trees of all templates of the template set.
+*/
+func (e *Engine) trees() []*parse.Tree {
+	var trees []*parse.Tree
+	switch t := e.templ.(type) {
+	case *texttemplate.Template:
+		for _, templ := range t.Templates() {
+			if templ.Tree != nil {
+				trees = append(trees, templ.Tree)
+			}
+		}
+	case *htmltemplate.Template:
+		for _, templ := range t.Templates() {
+			if templ.Tree != nil {
+				trees = append(trees, templ.Tree)
+			}
+		}
+	}
+	return trees
+}
+
+/*
+isMarked checks whether template starts with the marker of markTemplates (e.g. parsed before from same file).
+*/
+func isMarked(tree *parse.Tree) bool {
+	if len(tree.Root.Nodes) == 0 {
+		return false
+	}
+	node, ok := tree.Root.Nodes[0].(*parse.IfNode)
+	if !ok || len(node.Pipe.Cmds) == 0 || len(node.Pipe.Cmds[0].Args) == 0 {
+		return false
+	}
+	ident, ok := node.Pipe.Cmds[0].Args[0].(*parse.IdentifierNode)
+	return ok && ident.Ident == enterTemplateFunc
+}
+
+/*
+copyTemplateDirs returns a copy of the directories of the template files (e.g. for a clone).
+*/
+func (e *Engine) copyTemplateDirs() map[string]string {
+	dirs := make(map[string]string, len(e.templateDirs))
+	for name, dir := range e.templateDirs {
+		dirs[name] = dir
+	}
+	return dirs
+}
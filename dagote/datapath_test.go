@@ -0,0 +1,81 @@
+package dagote
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/*
+TestDataPathTemplate tests that data functions resolve relative paths against the directory of the template
+file defining the template being evaluated (data path mode template).
+*/
+func TestDataPathTemplate(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"p.json":              `{"who": "root"}`,
+		"inc/p.json":          `{"who": "inc"}`,
+		"inc/deep/p.json":     `{"who": "deep"}`,
+		"main.tmpl":           `{{ (readJSON "p.json").who }}|{{ template "p" . }}|{{ (readJSON "p.json").who }}`,
+		"inc/p.tmpl":          `{{ define "p" }}{{ (readJSON "p.json").who }}/{{ template "q" . }}+{{ (readJSON "p.json").who }}{{ end }}`,
+		"inc/deep/q.tmpl":     `{{ define "q" }}{{ (readJSON "p.json").who }}{{ end }}`,
+		"render.tmpl":         `{{ renderTo "p" "out.txt" . }}{{ (readJSON "p.json").who }}`,
+		"inc/deep/other.tmpl": `{{ (readJSON "p.json").who }}`,
+	}
+	for name, content := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	partials := []string{filepath.Join(dir, "inc", "p.tmpl"), filepath.Join(dir, "inc", "deep", "q.tmpl")}
+
+	tests := []struct {
+		name   string
+		format string
+		start  string
+		want   string
+		output string // content of out.txt (renderTo)
+	}{
+		{"text", "text", "main.tmpl", "root|inc/deep+inc|root", ""},
+		{"html", "html", "main.tmpl", "root|inc/deep+inc|root", ""},
+		{"start template in subdirectory", "text", "inc/deep/other.tmpl", "deep", ""},
+		{"renderTo", "text", "render.tmpl", "root", "inc/deep+inc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputRoot := t.TempDir()
+			engine, err := New(WithFormat(tt.format), WithDataPath("template", ""), WithOutputRoot(outputRoot))
+			if err != nil {
+				t.Fatal(err)
+			}
+			filenames := append([]string{filepath.Join(dir, filepath.FromSlash(tt.start))}, partials...)
+			if err := engine.ParseFiles(filenames...); err != nil {
+				t.Fatal(err)
+			}
+			// second execution uses the same executor
+			for i := 0; i < 2; i++ {
+				var out bytes.Buffer
+				if err := engine.Execute(&out); err != nil {
+					t.Fatal(err)
+				}
+				if out.String() != tt.want {
+					t.Errorf("output = %q, want %q", out.String(), tt.want)
+				}
+			}
+			if tt.output != "" {
+				data, err := os.ReadFile(filepath.Join(outputRoot, "out.txt"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != tt.output {
+					t.Errorf("renderTo output = %q, want %q", data, tt.output)
+				}
+			}
+		})
+	}
+}
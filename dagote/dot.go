@@ -0,0 +1,191 @@
+package dagote
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	toml "github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// DotTypes lists the supported dot types.
+var DotTypes = []string{"auto", "json", "yaml", "yamlall", "ndjson", "toml", "csv", "csvmap", "xml", "text", "lines"}
+
+/*
+parseDotData transforms dot data into given dot type (name is used in error messages, csvOpts for csv and csvmap).
+*/
+func parseDotData(data []byte, dottype, name string, csvOpts CSVOptions) (any, error) {
+	var dotdata any
+	var err error
+
+	switch strings.ToLower(dottype) {
+	case "json":
+		dotdata, err = parseJSON(data, name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to transform dot data to JSON, error=[%v]", err)
+		}
+	case "yaml":
+		dotdata, err = parseYAML(data, name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to transform dot data to YAML, error=[%v]", err)
+		}
+	case "csv":
+		dotdata, err = parseCSVWith(data, name, csvOpts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to transform dot data to CSV, error=[%v]", err)
+		}
+	case "csvmap":
+		dotdata, err = parseCSVMapWith(data, name, csvOpts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to transform dot data to CSVMap, error=[%v]", err)
+		}
+	case "text":
+		dotdata = string(data)
+	case "lines":
+		dotdata, err = parseLines(data, name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to transform dot data to LINES, error=[%v]", err)
+		}
+	case "xml":
+		dotdata, err = parseXML(data, name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to transform dot data to XML, error=[%v]", err)
+		}
+	case "toml":
+		dotdata, err = parseTOML(data, name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to transform dot data to TOML, error=[%v]", err)
+		}
+	case "yamlall", "ndjson":
+		return parseDotStream(bytes.NewReader(data), dottype, name)
+	default:
+		return nil, fmt.Errorf("unsupported dot type, type=[%v]", dottype)
+	}
+
+	return dotdata, nil
+}
+
+/*
+parseDotStream decodes streamable dot data (multi-document YAML, NDJSON) without buffering the whole input.
+*/
+func parseDotStream(r io.Reader, dottype, name string) (any, error) {
+	var dotdata []any
+	var err error
+	switch strings.ToLower(dottype) {
+	case "yamlall":
+		dotdata, err = parseYAMLAll(r, name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to transform dot data to YAMLALL, error=[%v]", err)
+		}
+	case "ndjson":
+		dotdata, err = parseNDJSON(r, name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to transform dot data to NDJSON, error=[%v]", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported dot type for streaming, type=[%v]", dottype)
+	}
+	return dotdata, nil
+}
+
+/*
+isStreamType checks whether dot type is parsed from stream (see parseDotStream).
+*/
+func isStreamType(dottype string) bool {
+	switch strings.ToLower(dottype) {
+	case "yamlall", "ndjson":
+		return true
+	}
+	return false
+}
+
+/*
+DetectTypeByExtension detects the dot type of a file by its extension (empty string if unknown).
+*/
+func DetectTypeByExtension(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return "json"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	case ".xml":
+		return "xml"
+	case ".csv":
+		return "csv"
+	}
+	return ""
+}
+
+/*
+SniffType detects the dot type of data by its content (json, xml, toml, yaml, csv, text).
+*/
+func SniffType(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return "text"
+	}
+
+	// JSON: object or array
+	if (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return "json"
+	}
+
+	// XML: well-formed document with root element
+	if trimmed[0] == '<' && isXML(trimmed) {
+		return "xml"
+	}
+
+	// TOML: at least one key/value pair or table
+	tomlMap := make(map[string]any)
+	if toml.Unmarshal(trimmed, &tomlMap) == nil && len(tomlMap) > 0 {
+		return "toml"
+	}
+
+	// YAML: mapping or sequence (a plain scalar is considered as text)
+	var yamlData any
+	if yaml.Unmarshal(trimmed, &yamlData) == nil {
+		switch yamlData.(type) {
+		case map[string]any, []any:
+			return "yaml"
+		}
+	}
+
+	// CSV: at least two records with same number (> 1) of fields
+	reader := csv.NewReader(bytes.NewReader(trimmed))
+	records, err := reader.ReadAll()
+	if err == nil && len(records) > 1 && len(records[0]) > 1 {
+		return "csv"
+	}
+
+	return "text"
+}
+
+/*
+isXML checks whether data is a well-formed XML document.
+*/
+func isXML(data []byte) bool {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	rootFound := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return rootFound
+		}
+		if err != nil {
+			return false
+		}
+		if _, ok := token.(xml.StartElement); ok {
+			rootFound = true
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package dagote
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+/*
+TestSniffType tests the detection of the dot type by content.
+*/
+func TestSniffType(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"empty", "", "text"},
+		{"blank", " \n\t", "text"},
+		{"json object", `{"a": 1}`, "json"},
+		{"json array", "\n[1, 2, 3]\n", "json"},
+		{"invalid json", `{"a": 1`, "text"},
+		{"xml", `<?xml version="1.0"?><root><a>1</a></root>`, "xml"},
+		{"malformed xml", `<root><a>1</root>`, "text"},
+		{"toml", "title = \"x\"\n[owner]\nname = \"y\"\n", "toml"},
+		{"yaml mapping", "a: 1\nb:\n  - x\n  - y\n", "yaml"},
+		{"yaml sequence", "- x\n- y\n", "yaml"},
+		{"yaml scalar", "just a sentence", "text"},
+		{"csv", "name,age\nanna,42\n", "csv"},
+		{"single column", "name\nanna\n", "text"},
+		{"single csv record", "name,age\n", "text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SniffType([]byte(tt.data)); got != tt.want {
+				t.Errorf("SniffType(%q) = %q, want %q", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+/*
+TestDetectTypeByExtension tests the detection of the dot type by file extension.
+*/
+func TestDetectTypeByExtension(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"data.json", "json"},
+		{"DATA.JSON", "json"},
+		{"events.ndjson", "ndjson"},
+		{"events.jsonl", "ndjson"},
+		{"config.yaml", "yaml"},
+		{"config.yml", "yaml"},
+		{"config.toml", "toml"},
+		{"feed.xml", "xml"},
+		{"export.csv", "csv"},
+		{"notes.txt", ""},
+		{"README", ""},
+		{"dir.json/file", ""},
+	}
+	for _, tt := range tests {
+		if got := DetectTypeByExtension(tt.filename); got != tt.want {
+			t.Errorf("DetectTypeByExtension(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+}
+
+/*
+TestLoadDotFileCache tests that dot files are parsed once per dot type and CSV dialect (shared data cache).
+*/
+func TestLoadDotFileCache(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		changed  string // same size, reverted modification time
+		dottype  string
+		options  map[string]any
+		wantType string
+		want     any
+	}{
+		{"json", "a.json", `{"a":1}`, `{"a":2}`, "auto", nil, "json", map[string]any{"a": float64(1)}},
+		{"sniffed type", "a.data", `{"a":1}`, `{"a":2}`, "auto", nil, "json", map[string]any{"a": float64(1)}},
+		{"ndjson", "a.ndjson", `{"a":1}`, `{"a":2}`, "auto", nil, "ndjson", []any{map[string]any{"a": float64(1)}}},
+		{"csv dialect", "a.csv", "a;b\n1;2\n", "a;b\n3;4\n", "csvmap", map[string]any{"comma": ";"}, "csvmap", []map[string]string{{"a": "1", "b": "2"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(dir, tt.filename)
+			err := os.WriteFile(filename, []byte(tt.content), 0666)
+			if err != nil {
+				t.Fatal(err)
+			}
+			modTime := time.Now().Add(-time.Hour)
+			if err = os.Chtimes(filename, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+			cache := NewDataCache()
+			load := func() (string, any) {
+				t.Helper()
+				e, err := New(WithDataCache(cache), WithDotOptions(tt.options))
+				if err != nil {
+					t.Fatal(err)
+				}
+				dottype, err := e.LoadDotFile(filename, tt.dottype)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return dottype, e.Dot()
+			}
+			load()
+			// unchanged size and modification time: cached data is used
+			if err = os.WriteFile(filename, []byte(tt.changed), 0666); err != nil {
+				t.Fatal(err)
+			}
+			if err = os.Chtimes(filename, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+			dottype, got := load()
+			if dottype != tt.wantType || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadDotFile(%q) = %v, %#v, want %v, %#v", tt.filename, dottype, got, tt.wantType, tt.want)
+			}
+		})
+	}
+
+	// other CSV dialect: file is parsed again
+	filename := filepath.Join(dir, "b.csv")
+	if err := os.WriteFile(filename, []byte("a;b\n1;2\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	cache := NewDataCache()
+	for _, comma := range []string{";", ","} {
+		e, err := New(WithDataCache(cache), WithDotOptions(map[string]any{"comma": comma}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = e.LoadDotFile(filename, "csv"); err != nil {
+			t.Fatal(err)
+		}
+		if got := len(e.Dot().([][]string)[0]); (comma == ";") != (got == 2) {
+			t.Errorf("LoadDotFile(comma %q) = %d column(s)", comma, got)
+		}
+	}
+}
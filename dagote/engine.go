@@ -0,0 +1,636 @@
+/*
+Package dagote allows usage of arbitrary JSON, YAML, TOML, CSV, XML, TEXT in Go templates.
+
+An Engine holds the configuration (format, delimiters, function groups, ...), the 'dot' (.) data
+and the parsed template set. Typical usage:
+
+	engine, err := dagote.New(dagote.WithFormat("html"))
+	err = engine.LoadDot(strings.NewReader(`{"name":"John"}`), "json")
+	err = engine.ParseTemplates(os.DirFS("templates"), "page.tmpl", "includes/*.tmpl")
+	err = engine.Execute(os.Stdout)
+*/
+package dagote
+
+import (
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// FunctionGroups lists the function groups available within the template set.
+var FunctionGroups = []string{"sprig", "data", "file", "query", "collection", "encode", "html", "output"}
+
+/*
+templateSet is a parsed text or html template set.
+*/
+type templateSet interface {
+	Name() string
+	Execute(wr io.Writer, data any) error
+	ExecuteTemplate(wr io.Writer, name string, data any) error
+}
+
+/*
+Engine parses and executes a text or html template set with 'dot' (.) data.
+*/
+type Engine struct {
+	format         string
+	leftDelim      string
+	rightDelim     string
+	groups         []string
+	outputRoot     string // absolute path, empty: writing files disabled
+	mergeStrategy  string
+	csvOptions     CSVOptions // dialect of csv and csvmap dot data
+	mergeKey       string
+	cache          *DataCache
+	fsys           fs.FS             // nil: local file system
+	dataPath       string            // base path mode of data functions (cwd, start, template, datadir)
+	dataDir        string            // base directory for mode datadir
+	startDir       string            // directory of start template (empty if unknown)
+	templateDirs   map[string]string // directories of template files (parse name -> directory)
+	sandbox        bool              // data functions restricted to readable directories
+	timeout        time.Duration     // 0 = no limit
+	maxOutputBytes int64             // 0 = no limit
+	maxReadBytes   int64             // 0 = no limit
+	partialOutput  string            // delete, keep
+	exec           *executor         // executable clone of template set (nil: not executed or template set changed)
+	tracker        *tracker          // shared by all clones
+
+	dot       any
+	dotLoaded bool
+	templ     templateSet
+}
+
+/*
+Option configures an Engine.
+*/
+type Option func(*Engine) error
+
+/*
+WithFormat sets the format of the template set (text, html).
+*/
+func WithFormat(format string) Option {
+	return func(e *Engine) error {
+		format = strings.ToLower(format)
+		if format != "text" && format != "html" {
+			return fmt.Errorf("format not supported, format=[%v]", format)
+		}
+		e.format = format
+		return nil
+	}
+}
+
+/*
+WithDelims sets the action delimiters of the templates.
+*/
+func WithDelims(left, right string) Option {
+	return func(e *Engine) error {
+		e.leftDelim = left
+		e.rightDelim = right
+		return nil
+	}
+}
+
+/*
+WithFunctions enables the given function groups (see FunctionGroups) only.
+*/
+func WithFunctions(groups ...string) Option {
+	return func(e *Engine) error {
+		err := ValidateFunctionGroups(groups)
+		if err != nil {
+			return err
+		}
+		e.groups = nil
+		for _, group := range groups {
+			group = strings.ToLower(strings.TrimSpace(group))
+			if group == "all" {
+				e.groups = FunctionGroups
+				return nil
+			}
+			e.groups = append(e.groups, group)
+		}
+		return nil
+	}
+}
+
+/*
+ValidateFunctionGroups checks whether all function groups are supported ('all' enables all groups).
+*/
+func ValidateFunctionGroups(groups []string) error {
+	for _, group := range groups {
+		group = strings.ToLower(strings.TrimSpace(group))
+		if group != "all" && !contains(FunctionGroups, group) {
+			return fmt.Errorf("function group not supported, group=[%v]", group)
+		}
+	}
+	return nil
+}
+
+/*
+WithOutputRoot enables the template functions 'writeFile' and 'renderTo' for files below the directory.
+*/
+func WithOutputRoot(dir string) Option {
+	return func(e *Engine) error {
+		if dir == "" {
+			dir = "."
+		}
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return fmt.Errorf("unable to determine absolute path of output root, directory=[%v], error=[%w]", dir, err)
+		}
+		e.outputRoot = absDir
+		return nil
+	}
+}
+
+/*
+WithMerge sets the list merge strategy (replace, append, key) for multiple dot data sources.
+The key identifies list elements (maps) for strategy 'key'.
+*/
+func WithMerge(strategy, key string) Option {
+	return func(e *Engine) error {
+		strategy = strings.ToLower(strategy)
+		switch strategy {
+		case "replace", "append", "key":
+		default:
+			return fmt.Errorf("merge strategy not supported, strategy=[%v]", strategy)
+		}
+		e.mergeStrategy = strategy
+		e.mergeKey = key
+		return nil
+	}
+}
+
+/*
+WithDotOptions sets the options of dot data (see CSVOptionNames, e.g. "comma": ";").
+*/
+func WithDotOptions(options map[string]any) Option {
+	return func(e *Engine) error {
+		csvOpts, err := ParseCSVOptions(options)
+		if err != nil {
+			return err
+		}
+		e.csvOptions = csvOpts
+		return nil
+	}
+}
+
+/*
+WithDataCache shares parsed data files via cache (e.g. between multiple engines).
+*/
+func WithDataCache(cache *DataCache) Option {
+	return func(e *Engine) error {
+		e.cache = cache
+		return nil
+	}
+}
+
+/*
+New creates an engine (defaults: format text, delimiters '{{' '}}', all function groups, merge strategy replace).
+*/
+func New(options ...Option) (*Engine, error) {
+	e := &Engine{
+		format:        "text",
+		leftDelim:     "{{",
+		rightDelim:    "}}",
+		groups:        FunctionGroups,
+		mergeStrategy: "replace",
+		mergeKey:      "name",
+		dataPath:      "cwd",
+		partialOutput: "delete",
+		templateDirs:  make(map[string]string),
+		tracker:       newTracker(),
+	}
+	for _, option := range options {
+		err := option(e)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+/*
+Format returns the format of the template set (text, html).
+*/
+func (e *Engine) Format() string {
+	return e.format
+}
+
+/*
+LeftDelim returns the left action delimiter of templates.
+*/
+func (e *Engine) LeftDelim() string {
+	return e.leftDelim
+}
+
+/*
+LoadDot reads dot data from reader and transforms it into dot type (auto, json, yaml, toml, csv, csvmap, xml, text, lines).
+Dot data of multiple calls is deep-merged (later data takes precedence).
+*/
+func (e *Engine) LoadDot(r io.Reader, dottype string) error {
+	if isStreamType(dottype) {
+		dotdata, err := parseDotStream(r, dottype, "dot data")
+		if err != nil {
+			return err
+		}
+		return e.mergeDot(dotdata)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("unable to read dot data, error=[%w]", err)
+	}
+	if strings.ToLower(dottype) == "auto" {
+		dottype = SniffType(data)
+	}
+	dotdata, err := parseDotData(data, dottype, "dot data", e.csvOptions)
+	if err != nil {
+		return err
+	}
+	return e.mergeDot(dotdata)
+}
+
+/*
+LoadDotFile reads dot data from file and transforms it into dot type (see LoadDot).
+Dot type 'auto' detects the type by file extension or content. Returns the dot type used.
+Parsed dot files are cached by dot type and CSV dialect (see WithDataCache).
+*/
+func (e *Engine) LoadDotFile(filename, dottype string) (string, error) {
+	e.recordRead(filename)
+	e.addReadRoot(filepath.Dir(filename))
+	dottype = strings.ToLower(dottype)
+	if dottype == "auto" && DetectTypeByExtension(filename) != "" {
+		dottype = DetectTypeByExtension(filename)
+	}
+	if dottype == "auto" {
+		// type detected by content is cached as well
+		if cached, ok := e.lookupCache("dottype", filename); ok {
+			dottype = cached.(string)
+		}
+	}
+	kind := e.dotCacheKind(dottype)
+	if cached, ok := e.lookupCache(kind, filename); ok {
+		return dottype, e.mergeDot(cached)
+	}
+	if isStreamType(dottype) {
+		file, err := e.openFile(filename)
+		if err != nil {
+			return "", fmt.Errorf("unable to open dot file, file=[%v], error=[%w]", filename, err)
+		}
+		defer file.Close()
+		dotdata, err := parseDotStream(file, dottype, filename)
+		if err != nil {
+			return "", err
+		}
+		e.storeCache(kind, filename, dotdata)
+		return dottype, e.mergeDot(dotdata)
+	}
+	data, err := e.readFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("unable to read dot file, file=[%v], error=[%w]", filename, err)
+	}
+	if dottype == "auto" {
+		dottype = SniffType(data)
+		e.storeCache("dottype", filename, dottype)
+		kind = e.dotCacheKind(dottype)
+	}
+	dotdata, err := parseDotData(data, dottype, filename, e.csvOptions)
+	if err != nil {
+		return "", err
+	}
+	e.storeCache(kind, filename, dotdata)
+	return dottype, e.mergeDot(dotdata)
+}
+
+/*
+dotCacheKind returns the kind of cached dot data (dot type, CSV dialect for csv and csvmap).
+*/
+func (e *Engine) dotCacheKind(dottype string) string {
+	switch dottype {
+	case "csv", "csvmap":
+		return fmt.Sprintf("dot:%s%+v", dottype, e.csvOptions)
+	}
+	return "dot:" + dottype
+}
+
+/*
+mergeDot merges dot data with already loaded dot data.
+*/
+func (e *Engine) mergeDot(dotdata any) error {
+	if !e.dotLoaded {
+		e.dot = dotdata
+		e.dotLoaded = true
+		return nil
+	}
+	merged, err := MergeData(e.dot, dotdata, e.mergeStrategy, e.mergeKey)
+	if err != nil {
+		return err
+	}
+	e.dot = merged
+	return nil
+}
+
+/*
+SetDot sets the dot data (replaces already loaded dot data).
+*/
+func (e *Engine) SetDot(dotdata any) {
+	e.dot = dotdata
+	e.dotLoaded = true
+}
+
+/*
+Dot returns the dot data.
+*/
+func (e *Engine) Dot() any {
+	return e.dot
+}
+
+/*
+Parse parses template text as named template. The first template parsed is the start template.
+*/
+func (e *Engine) Parse(name, text string) error {
+	_, err := e.parse(name, text, "")
+	return err
+}
+
+/*
+parse parses template text (of file in directory dir, empty if unknown) as named template into the template set.
+Returns the named template of the set.
+*/
+func (e *Engine) parse(name, text, dir string) (templateSet, error) {
+	if e.templ == nil {
+		e.startDir = dir
+		switch e.format {
+		case "html":
+			e.templ = htmltemplate.New(name).Delims(e.leftDelim, e.rightDelim).Funcs(e.FuncMap())
+		default:
+			e.templ = texttemplate.New(name).Delims(e.leftDelim, e.rightDelim).Funcs(e.FuncMap())
+		}
+	}
+	var templ templateSet
+	var err error
+	switch t := e.templ.(type) {
+	case *texttemplate.Template:
+		if name != t.Name() {
+			t = t.New(name)
+		}
+		templ, err = t.Parse(text)
+	case *htmltemplate.Template:
+		if name != t.Name() {
+			t = t.New(name)
+		}
+		templ, err = t.Parse(text)
+	}
+	if err != nil {
+		return nil, err
+	}
+	e.exec = nil
+	e.addReadRoot(dir)
+	if dir != "" {
+		e.templateDirs[name] = dir
+	}
+	if e.dataPath == "template" {
+		err = e.markTemplates(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return templ, nil
+}
+
+/*
+ParseFiles parses template files (named by base name). The first file is the start template.
+*/
+func (e *Engine) ParseFiles(filenames ...string) error {
+	for _, filename := range filenames {
+		e.tracker.recordRead(filename)
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("unable to read template file, file=[%v], error=[%w]", filename, err)
+		}
+		_, err = e.parse(filepath.Base(filename), string(data), filepath.Dir(filename))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+ParseTemplates parses all files of file system matching the patterns (named by base name).
+The first file matched is the start template.
+*/
+func (e *Engine) ParseTemplates(fsys fs.FS, patterns ...string) error {
+	var filenames []string
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return fmt.Errorf("unable to expand pattern, pattern=[%v], error=[%w]", pattern, err)
+		}
+		for _, match := range matches {
+			info, err := fs.Stat(fsys, match)
+			if err != nil {
+				return fmt.Errorf("unable to stat template file, file=[%v], error=[%w]", match, err)
+			}
+			if !info.IsDir() {
+				filenames = append(filenames, match)
+			}
+		}
+	}
+	if len(filenames) == 0 {
+		return errors.New("no template file found for parsing")
+	}
+	for _, filename := range filenames {
+		data, err := fs.ReadFile(fsys, filename)
+		if err != nil {
+			return fmt.Errorf("unable to read template file, file=[%v], error=[%w]", filename, err)
+		}
+		_, err = e.parse(path.Base(filename), string(data), path.Dir(filename))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Name returns the name of the start template (empty if nothing parsed).
+*/
+func (e *Engine) Name() string {
+	if e.templ == nil {
+		return ""
+	}
+	return e.templ.Name()
+}
+
+/*
+TemplateNames returns the (sorted) names of all templates defined in the template set.
+*/
+func (e *Engine) TemplateNames() []string {
+	var names []string
+	switch t := e.templ.(type) {
+	case *texttemplate.Template:
+		for _, template := range t.Templates() {
+			names = append(names, template.Name())
+		}
+	case *htmltemplate.Template:
+		for _, template := range t.Templates() {
+			names = append(names, template.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+/*
+Execute executes the start template with the dot data.
+*/
+func (e *Engine) Execute(w io.Writer) error {
+	return e.ExecuteData(w, e.dot)
+}
+
+/*
+ExecuteData executes the start template with the given data as dot data.
+*/
+func (e *Engine) ExecuteData(w io.Writer, data any) error {
+	if e.templ == nil {
+		return errors.New("no template parsed")
+	}
+	return e.execute(w, data)
+}
+
+/*
+Clone returns a copy of the engine (e.g. one per worker) with a clone of the template set.
+Dot data, data cache and tracking of files read and written are shared.
+*/
+func (e *Engine) Clone() (*Engine, error) {
+	clone := *e
+	clone.exec = nil
+	if e.templ == nil {
+		return &clone, nil
+	}
+	var err error
+	switch t := e.templ.(type) {
+	case *texttemplate.Template:
+		clone.templ, err = t.Clone()
+	case *htmltemplate.Template:
+		clone.templ, err = t.Clone()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to clone template set, error=[%w]", err)
+	}
+	clone.templateDirs = e.copyTemplateDirs()
+	return &clone, nil
+}
+
+/*
+CloneWith returns a clone of the engine with an additional template, which is the start template of the clone.
+*/
+func (e *Engine) CloneWith(name, text string) (*Engine, error) {
+	return e.cloneWith(name, text, "")
+}
+
+/*
+CloneWithFile returns a clone of the engine with an additional template read from file, which is the start template of the clone.
+*/
+func (e *Engine) CloneWithFile(name, filename string) (*Engine, error) {
+	e.tracker.recordRead(filename)
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read template file, file=[%v], error=[%w]", filename, err)
+	}
+	return e.cloneWith(name, string(data), filepath.Dir(filename))
+}
+
+/*
+cloneWith returns a clone of the engine with an additional template (of file in directory dir) as start template.
+*/
+func (e *Engine) cloneWith(name, text, dir string) (*Engine, error) {
+	if e.templ == nil {
+		clone := *e
+		clone.exec = nil
+		clone.templateDirs = e.copyTemplateDirs()
+		_, err := clone.parse(name, text, dir)
+		if err != nil {
+			return nil, err
+		}
+		return &clone, nil
+	}
+	clone, err := e.Clone()
+	if err != nil {
+		return nil, err
+	}
+	clone.templ, err = clone.parse(name, text, dir)
+	if err != nil {
+		return nil, err
+	}
+	clone.startDir = dir
+	return clone, nil
+}
+
+/*
+NewTextTemplate parses a text template (e.g. a file name template) with delimiters and functions of the engine.
+*/
+func (e *Engine) NewTextTemplate(name, text string) (*texttemplate.Template, error) {
+	return texttemplate.New(name).Delims(e.leftDelim, e.rightDelim).Funcs(e.FuncMap()).Parse(text)
+}
+
+/*
+setFuncs adds functions to (or replaces functions of) the template set.
+*/
+func setFuncs(templ templateSet, funcs map[string]any) {
+	switch t := templ.(type) {
+	case *texttemplate.Template:
+		t.Funcs(funcs)
+	case *htmltemplate.Template:
+		t.Funcs(funcs)
+	}
+}
+
+/*
+RecordRead records a file read outside of the engine (e.g. a file copied verbatim), so that it is part of ReadFiles.
+*/
+func (e *Engine) RecordRead(filename string) {
+	e.tracker.recordRead(filename)
+}
+
+/*
+ReadFiles returns the (sorted, absolute) names of all files read (templates, dot files, files read by template functions).
+*/
+func (e *Engine) ReadFiles() []string {
+	return e.tracker.readFiles()
+}
+
+/*
+ProducedFiles returns the (sorted, absolute) names of all additional files written by template functions.
+*/
+func (e *Engine) ProducedFiles() []string {
+	return e.tracker.producedFiles()
+}
+
+/*
+OutputRoot returns the absolute path of the output root (empty if writing files is disabled).
+*/
+func (e *Engine) OutputRoot() string {
+	return e.outputRoot
+}
+
+/*
+contains checks whether list contains value.
+*/
+func contains(list []string, value string) bool {
+	for _, element := range list {
+		if element == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+package dagote
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+/*
+WithFS resolves the file names of all data loaders (readXXX, fileXXX, LoadDotFile) against the file system
+(e.g. embed.FS, zip archive, overlay) instead of the local file system.
+File names must be relative slash-separated paths within the file system.
+*/
+func WithFS(fsys fs.FS) Option {
+	return func(e *Engine) error {
+		e.fsys = fsys
+		return nil
+	}
+}
+
+/*
+FS returns the file system used by the data loaders (nil: local file system).
+*/
+func (e *Engine) FS() fs.FS {
+	return e.fsys
+}
+
+/*
+fsPath converts file name into path within file system (e.g. './data/x.json' -> 'data/x.json').
+*/
+func fsPath(filename string) (string, error) {
+	name := path.Clean(filepath.ToSlash(filename))
+	if !fs.ValidPath(name) {
+		return "", fmt.Errorf("invalid path for file system (must be relative, without '..'), file=[%v]", filename)
+	}
+	return name, nil
+}
+
+/*
+readFile reads file from file system of engine (or local file system), reads beyond the read limit
+and reads of a stopped execution fail.
+*/
+func (e *Engine) readFile(filename string) ([]byte, error) {
+	if e.maxReadBytes == 0 && e.running() == nil {
+		if e.fsys == nil {
+			return os.ReadFile(filename)
+		}
+		name, err := fsPath(filename)
+		if err != nil {
+			return nil, err
+		}
+		return fs.ReadFile(e.fsys, name)
+	}
+	file, err := e.openFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+/*
+openFile opens file of file system of engine (or local file system) for streaming, reads beyond the read limit
+and reads of a stopped execution fail.
+*/
+func (e *Engine) openFile(filename string) (io.ReadCloser, error) {
+	err := e.running().err()
+	if err != nil {
+		return nil, err
+	}
+	open := openLocal(filename)
+	if e.fsys != nil {
+		name, err := fsPath(filename)
+		if err != nil {
+			return nil, err
+		}
+		open = openFS(e.fsys, name)
+	}
+	file, err := open()
+	if err != nil {
+		return nil, err
+	}
+	if e.maxReadBytes > 0 {
+		file = &limitReader{ReadCloser: file, remaining: e.maxReadBytes, limit: e.maxReadBytes, filename: filename}
+	}
+	if run := e.running(); run != nil {
+		file = &stopReader{ReadCloser: file, run: run}
+	}
+	return file, nil
+}
+
+/*
+stat returns file info from file system of engine (or local file system).
+*/
+func (e *Engine) stat(filename string) (fs.FileInfo, error) {
+	err := e.running().err()
+	if err != nil {
+		return nil, err
+	}
+	if e.fsys == nil {
+		return os.Stat(filename)
+	}
+	name, err := fsPath(filename)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Stat(e.fsys, name)
+}
+
+/*
+recordRead records file read (only files of local file system can be watched).
+*/
+func (e *Engine) recordRead(filename string) {
+	if e.fsys == nil {
+		e.tracker.recordRead(filename)
+	}
+}
+
+/*
+lookupCache returns parsed data of file from data cache (only files of local file system are cached,
+nothing is returned to a stopped execution).
+*/
+func (e *Engine) lookupCache(kind, filename string) (any, bool) {
+	if e.fsys != nil || e.running().err() != nil {
+		return nil, false
+	}
+	return e.cache.lookup(kind, filename, e.maxReadBytes)
+}
+
+/*
+storeCache stores parsed data of file in data cache (only files of local file system are cached).
+*/
+func (e *Engine) storeCache(kind, filename string, value any) {
+	if e.fsys == nil {
+		e.cache.store(kind, filename, value)
+	}
+}
+
+/*
+OpenZipFS reads zip archive into memory and returns it as file system.
+*/
+func OpenZipFS(filename string) (fs.FS, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read zip archive, file=[%v], error=[%w]", filename, err)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to open zip archive, file=[%v], error=[%w]", filename, err)
+	}
+	return reader, nil
+}
+
+/*
+SplitArchivePath splits a path of the form 'bundle.zip!templates/*.tmpl' into archive and path within archive.
+Returns false if the path doesn't refer to a zip archive.
+*/
+func SplitArchivePath(name string) (archive, inner string, ok bool) {
+	archive, inner, ok = strings.Cut(name, "!")
+	if !ok || !strings.EqualFold(filepath.Ext(archive), ".zip") {
+		return "", "", false
+	}
+	return archive, inner, true
+}
+
+/*
+overlayFS combines file systems, the first file system containing a file wins.
+*/
+type overlayFS []fs.FS
+
+/*
+NewOverlayFS combines file systems into one file system. Files are searched in given order of the layers.
+*/
+func NewOverlayFS(layers ...fs.FS) fs.FS {
+	return overlayFS(layers)
+}
+
+/*
+Open opens file of first layer containing the file (fs.FS interface).
+*/
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, layer := range o {
+		file, err := layer.Open(name)
+		if err == nil {
+			return file, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
@@ -0,0 +1,740 @@
+package dagote
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/sprig/v3"
+	xml "github.com/clbanning/mxj/v2"
+	toml "github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+/*
+FuncMap returns the functions (of enabled function groups) available within the template set.
+*/
+func (e *Engine) FuncMap() map[string]any {
+	groups := e.loaderFuncs()
+	groups["query"] = queryFuncs()
+	groups["collection"] = collectionFuncs()
+	groups["encode"] = encodeFuncs()
+	groups["html"] = map[string]any{
+		"toTypeHTML": toTypeHTML,
+		"toTypeCSS":  toTypeCSS,
+		"toTypeJS":   toTypeJS,
+		"toTypeURL":  toTypeURL,
+	}
+	groups["output"] = map[string]any{
+		"writeFile": e.WriteFile,
+		"renderTo":  renderTo,
+	}
+
+	// sprig first, functions of other groups replace sprig functions with same name (e.g. pluck, chunk)
+	funcs := make(map[string]any)
+	if contains(e.groups, "sprig") {
+		for name, f := range sprig.GenericFuncMap() {
+			funcs[name] = f
+		}
+	}
+	for _, group := range e.groups {
+		for name, f := range groups[group] {
+			funcs[name] = f
+		}
+	}
+	return funcs
+}
+
+/*
+ReadJSON reads JSON from file and unmarshals to any (object, array or scalar).
+*/
+func (e *Engine) ReadJSON(filename string) (any, error) {
+	if filename == "" {
+		return nil, errors.New("readJSON needs a filename")
+	}
+	err := e.checkRead(filename)
+	if err != nil {
+		return nil, err
+	}
+	e.recordRead(filename)
+	if cached, ok := e.lookupCache("json", filename); ok {
+		return cached, nil
+	}
+	jsonRaw, err := e.readFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read JSON file, file=[%v], error=[%w]", filename, err)
+	}
+	jsonData, err := parseJSON(jsonRaw, filename)
+	if err != nil {
+		return nil, err
+	}
+	e.storeCache("json", filename, jsonData)
+	return jsonData, nil
+}
+
+/*
+ReadJSONMap reads JSON from file and unmarshals to map of any (top level must be an object).
+*/
+func (e *Engine) ReadJSONMap(filename string) (map[string]any, error) {
+	jsonData, err := e.ReadJSON(filename)
+	if err != nil {
+		return nil, err
+	}
+	jsonMap, ok := jsonData.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected JSON shape, file=[%v], expected=[object], found=[%v]", filename, jsonShape(jsonData))
+	}
+	return jsonMap, nil
+}
+
+/*
+ReadJSONArray reads JSON from file and unmarshals to slice of any (top level must be an array).
+*/
+func (e *Engine) ReadJSONArray(filename string) ([]any, error) {
+	jsonData, err := e.ReadJSON(filename)
+	if err != nil {
+		return nil, err
+	}
+	jsonArray, ok := jsonData.([]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected JSON shape, file=[%v], expected=[array], found=[%v]", filename, jsonShape(jsonData))
+	}
+	return jsonArray, nil
+}
+
+/*
+jsonShape returns the JSON type name of unmarshaled data.
+*/
+func jsonShape(data any) string {
+	switch data.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", data)
+}
+
+/*
+parseJSON unmarshals JSON data to any (object, array or scalar).
+*/
+func parseJSON(jsonRaw []byte, filename string) (any, error) {
+	var jsonData any
+	err := json.Unmarshal(jsonRaw, &jsonData)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal JSON data, file=[%v], error=[%w]", filename, err)
+	}
+	return jsonData, nil
+}
+
+/*
+ReadYAML reads YAML from file and unmarshals to any (mapping, sequence or scalar).
+*/
+func (e *Engine) ReadYAML(filename string) (any, error) {
+	if filename == "" {
+		return nil, errors.New("readYAML needs a filename")
+	}
+	err := e.checkRead(filename)
+	if err != nil {
+		return nil, err
+	}
+	e.recordRead(filename)
+	if cached, ok := e.lookupCache("yaml", filename); ok {
+		return cached, nil
+	}
+	yamlRaw, err := e.readFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read YAML file, file=[%v], error=[%w]", filename, err)
+	}
+	yamlData, err := parseYAML(yamlRaw, filename)
+	if err != nil {
+		return nil, err
+	}
+	e.storeCache("yaml", filename, yamlData)
+	return yamlData, nil
+}
+
+/*
+parseYAML unmarshals YAML data to any (mapping, sequence or scalar, empty document: empty map).
+*/
+func parseYAML(yamlRaw []byte, filename string) (any, error) {
+	var yamlData any
+	err := yaml.Unmarshal(yamlRaw, &yamlData)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal YAML data, file=[%v], error=[%w]", filename, err)
+	}
+	if yamlData == nil {
+		return make(map[string]any), nil
+	}
+	return yamlData, nil
+}
+
+/*
+ReadYAMLAll reads all documents of multi-document YAML file (separated by '---') into slice of any (streaming).
+*/
+func (e *Engine) ReadYAMLAll(filename string) ([]any, error) {
+	if filename == "" {
+		return nil, errors.New("readYAMLAll needs a filename")
+	}
+	err := e.checkRead(filename)
+	if err != nil {
+		return nil, err
+	}
+	e.recordRead(filename)
+	if cached, ok := e.lookupCache("yamlall", filename); ok {
+		return cached.([]any), nil
+	}
+	file, err := e.openFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open YAML file, file=[%v], error=[%w]", filename, err)
+	}
+	defer file.Close()
+	documents, err := parseYAMLAll(file, filename)
+	if err != nil {
+		return nil, err
+	}
+	e.storeCache("yamlall", filename, documents)
+	return documents, nil
+}
+
+/*
+parseYAMLAll decodes all YAML documents of stream into slice of any (empty documents are skipped).
+*/
+func parseYAMLAll(r io.Reader, filename string) ([]any, error) {
+	documents := []any{}
+	decoder := yaml.NewDecoder(r)
+	for i := 1; ; i++ {
+		var document any
+		err := decoder.Decode(&document)
+		if err == io.EOF {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to unmarshal YAML data, file=[%v], document=[%d], error=[%w]", filename, i, err)
+		}
+		if document != nil {
+			documents = append(documents, document)
+		}
+	}
+}
+
+/*
+ReadNDJSON reads newline-delimited JSON (JSON Lines) file into slice of any, one element per line (streaming).
+*/
+func (e *Engine) ReadNDJSON(filename string) ([]any, error) {
+	if filename == "" {
+		return nil, errors.New("readNDJSON needs a filename")
+	}
+	err := e.checkRead(filename)
+	if err != nil {
+		return nil, err
+	}
+	e.recordRead(filename)
+	if cached, ok := e.lookupCache("ndjson", filename); ok {
+		return cached.([]any), nil
+	}
+	file, err := e.openFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open NDJSON file, file=[%v], error=[%w]", filename, err)
+	}
+	defer file.Close()
+	records, err := parseNDJSON(file, filename)
+	if err != nil {
+		return nil, err
+	}
+	e.storeCache("ndjson", filename, records)
+	return records, nil
+}
+
+/*
+parseNDJSON decodes JSON values line by line into slice of any (blank lines are skipped).
+*/
+func parseNDJSON(r io.Reader, filename string) ([]any, error) {
+	records := []any{}
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		text, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("unable to read NDJSON data, file=[%v], line=[%d], error=[%w]", filename, line, err)
+		}
+		if trimmed := bytes.TrimSpace(text); len(trimmed) > 0 {
+			var record any
+			if jsonErr := json.Unmarshal(trimmed, &record); jsonErr != nil {
+				return nil, fmt.Errorf("unable to unmarshal NDJSON data, file=[%v], line=[%d], error=[%w]", filename, line, jsonErr)
+			}
+			records = append(records, record)
+		}
+		if err == io.EOF {
+			return records, nil
+		}
+	}
+}
+
+/*
+ReadCSV reads all records of csv file into two-dimensional slice of strings.
+*/
+func (e *Engine) ReadCSV(filename string) ([][]string, error) {
+	if filename == "" {
+		return nil, errors.New("readCSV needs a filename")
+	}
+	err := e.checkRead(filename)
+	if err != nil {
+		return nil, err
+	}
+	e.recordRead(filename)
+	if cached, ok := e.lookupCache("csv", filename); ok {
+		return cached.([][]string), nil
+	}
+	csvRaw, err := e.readFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CSV file, file=[%v], error=[%w]", filename, err)
+	}
+	records, err := parseCSV(csvRaw, filename)
+	if err != nil {
+		return nil, err
+	}
+	e.storeCache("csv", filename, records)
+	return records, nil
+}
+
+/*
+parseCSV reads all records of csv data (default dialect) into two-dimensional slice of strings.
+*/
+func parseCSV(csvRaw []byte, filename string) ([][]string, error) {
+	return parseCSVWith(csvRaw, filename, CSVOptions{})
+}
+
+/*
+ReadCSVMap reads all records of csv file into slice of maps (ragged rows and duplicate column names fail, see readCSVMapWith).
+*/
+func (e *Engine) ReadCSVMap(filename string) ([]map[string]string, error) {
+	if filename == "" {
+		return nil, errors.New("readCSVMap needs a filename")
+	}
+	err := e.checkRead(filename)
+	if err != nil {
+		return nil, err
+	}
+	e.recordRead(filename)
+	if cached, ok := e.lookupCache("csvmap", filename); ok {
+		return cached.([]map[string]string), nil
+	}
+	csvRaw, err := e.readFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CSV file, file=[%v], error=[%w]", filename, err)
+	}
+	returnMap, err := parseCSVMap(csvRaw, filename)
+	if err != nil {
+		return nil, err
+	}
+	e.storeCache("csvmap", filename, returnMap)
+	return returnMap, nil
+}
+
+/*
+parseCSVMap reads all records of csv data (default dialect) into slice of maps.
+*/
+func parseCSVMap(csvRaw []byte, filename string) ([]map[string]string, error) {
+	return parseCSVMapWith(csvRaw, filename, CSVOptions{})
+}
+
+/*
+parseCSVMapWith reads all records of csv data with given dialect into slice of maps.
+*/
+func parseCSVMapWith(csvRaw []byte, filename string, opts CSVOptions) ([]map[string]string, error) {
+	header, records, _, err := csvMapRecords(csvRaw, filename, opts)
+	if err != nil {
+		return nil, err
+	}
+	returnMap := []map[string]string{}
+	for _, record := range records {
+		// for each cell, map[string]string k=header v=value
+		line := make(map[string]string, len(header))
+		for i, column := range header {
+			if column != "" {
+				line[column] = record[i]
+			}
+		}
+		returnMap = append(returnMap, line)
+	}
+	return returnMap, nil
+}
+
+/*
+ReadText reads text file into string.
+*/
+func (e *Engine) ReadText(filename string) (string, error) {
+	if filename == "" {
+		return "", errors.New("readText needs a filename")
+	}
+	err := e.checkRead(filename)
+	if err != nil {
+		return "", err
+	}
+	e.recordRead(filename)
+	if cached, ok := e.lookupCache("text", filename); ok {
+		return cached.(string), nil
+	}
+	data, err := e.readFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("unable to read text file, file=[%v], error=[%w]", filename, err)
+	}
+	e.storeCache("text", filename, string(data))
+	return string(data), nil
+}
+
+/*
+ReadLines reads all lines of text file into slice of strings.
+*/
+func (e *Engine) ReadLines(filename string) ([]string, error) {
+	if filename == "" {
+		return nil, errors.New("readTextLines needs a filename")
+	}
+	err := e.checkRead(filename)
+	if err != nil {
+		return nil, err
+	}
+	e.recordRead(filename)
+	if cached, ok := e.lookupCache("lines", filename); ok {
+		return cached.([]string), nil
+	}
+	file, err := e.readFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read text lines file, file=[%v], error=[%w]", filename, err)
+	}
+	lines, err := parseLines(file, filename)
+	if err != nil {
+		return lines, err
+	}
+	e.storeCache("lines", filename, lines)
+	return lines, nil
+}
+
+/*
+parseLines splits text data into slice of lines.
+*/
+func parseLines(file []byte, filename string) ([]string, error) {
+	var lines []string
+	buf := bytes.NewBuffer(file)
+	for {
+		line, err := buf.ReadString('\n')
+		if len(line) == 0 {
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				return lines, fmt.Errorf("unable to read string, file=[%v], error=[%w]", filename, err)
+			}
+		}
+		line = strings.TrimSuffix(line, "\n")
+		lines = append(lines, line)
+		if err != nil && err != io.EOF {
+			return lines, fmt.Errorf("unable to read string, file=[%v], error=[%w]", filename, err)
+		}
+	}
+	return lines, nil
+}
+
+/*
+ReadXML reads XML from file and unmarshals to map of any.
+*/
+func (e *Engine) ReadXML(filename string) (map[string]any, error) {
+	if filename == "" {
+		return nil, errors.New("readXML needs a filename")
+	}
+	err := e.checkRead(filename)
+	if err != nil {
+		return nil, err
+	}
+	e.recordRead(filename)
+	if cached, ok := e.lookupCache("xml", filename); ok {
+		return cached.(map[string]any), nil
+	}
+	xmlRaw, err := e.readFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read XML file, file=[%v], error=[%w]", filename, err)
+	}
+	xmlMap, err := parseXML(xmlRaw, filename)
+	if err != nil {
+		return nil, err
+	}
+	e.storeCache("xml", filename, xmlMap)
+	return xmlMap, nil
+}
+
+/*
+parseXML unmarshals XML data to map of any (content of root element).
+*/
+func parseXML(xmlRaw []byte, filename string) (map[string]any, error) {
+	xmlRoot, err := xml.NewMapXml(xmlRaw)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse XML data, file=[%v], error=[%w]", filename, err)
+	}
+	xmlRootName, err := xmlRoot.Root()
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal XML data, file=[%v], error=[%w]", filename, err)
+	}
+	return xmlRoot[xmlRootName].(map[string]any), nil
+}
+
+/*
+ReadTOML reads TOML from file and unmarshals to any (a TOML document is always a table: map of any).
+*/
+func (e *Engine) ReadTOML(filename string) (any, error) {
+	if filename == "" {
+		return nil, errors.New("readTOML needs a filename")
+	}
+	err := e.checkRead(filename)
+	if err != nil {
+		return nil, err
+	}
+	e.recordRead(filename)
+	if cached, ok := e.lookupCache("toml", filename); ok {
+		return cached, nil
+	}
+	tomlRaw, err := e.readFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read TOML file, file=[%v], error=[%w]", filename, err)
+	}
+	tomlMap, err := parseTOML(tomlRaw, filename)
+	if err != nil {
+		return nil, err
+	}
+	e.storeCache("toml", filename, tomlMap)
+	return tomlMap, nil
+}
+
+/*
+parseTOML unmarshals TOML data to map of any.
+*/
+func parseTOML(tomlRaw []byte, filename string) (map[string]any, error) {
+	tomlMap := make(map[string]any)
+	err := toml.Unmarshal(tomlRaw, &tomlMap)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal TOML data, file=[%v], error=[%w]", filename, err)
+	}
+	return tomlMap, nil
+}
+
+/*
+FileExists checks whether file or directory exists under given path.
+Errors other than 'not exist' (e.g. permission denied) are returned.
+*/
+func (e *Engine) FileExists(filename string) (bool, error) {
+	if filename == "" {
+		return false, errors.New("fileExists needs a filename")
+	}
+	err := e.checkRead(filename)
+	if err != nil {
+		return false, err
+	}
+	e.recordRead(filename)
+	_, err = e.stat(filename)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return false, fmt.Errorf("unable to check existence of file, file=[%v], error=[%w]", filename, err)
+}
+
+/*
+FileStat returns file info structure describing file.
+*/
+func (e *Engine) FileStat(filename string) (os.FileInfo, error) {
+	if filename == "" {
+		return nil, errors.New("fileStat needs a filename")
+	}
+	err := e.checkRead(filename)
+	if err != nil {
+		return nil, err
+	}
+	e.recordRead(filename)
+	info, err := e.stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+/*
+FileRead reads arbitrary file into slice of bytes.
+*/
+func (e *Engine) FileRead(filename string) ([]byte, error) {
+	if filename == "" {
+		return nil, errors.New("fileRead needs a filename")
+	}
+	err := e.checkRead(filename)
+	if err != nil {
+		return nil, err
+	}
+	e.recordRead(filename)
+	data, err := e.readFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file, file=[%v], error=[%w]", filename, err)
+	}
+	return data, nil
+}
+
+/*
+WriteFile writes content into file (below output root) and returns an empty string.
+*/
+func (e *Engine) WriteFile(filename string, content any) (string, error) {
+	if filename == "" {
+		return "", errors.New("writeFile needs a filename")
+	}
+	err := e.running().err()
+	if err != nil {
+		return "", err
+	}
+	path, err := e.resolveOutputPath(filename)
+	if err != nil {
+		return "", err
+	}
+	var data []byte
+	switch c := content.(type) {
+	case string:
+		data = []byte(c)
+	case []byte:
+		data = c
+	default:
+		data = []byte(fmt.Sprint(c))
+	}
+	if e.maxOutputBytes > 0 && int64(len(data)) > e.maxOutputBytes {
+		return "", fmt.Errorf("%w, file=[%v], limit=[%d bytes]", ErrOutputLimit, path, e.maxOutputBytes)
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0777)
+	if err != nil {
+		return "", fmt.Errorf("unable to create directory, directory=[%v], error=[%w]", filepath.Dir(path), err)
+	}
+	err = os.WriteFile(path, data, 0666)
+	if err != nil {
+		return "", fmt.Errorf("unable to write file, file=[%v], error=[%w]", path, err)
+	}
+	e.tracker.recordProduced(path)
+	return "", nil
+}
+
+/*
+renderTo is the placeholder for function 'renderTo' until bound to an executor (see Engine.executor).
+*/
+func renderTo(name, filename string, data any) (string, error) {
+	return "", errors.New("renderTo not bound to template set")
+}
+
+/*
+renderTo executes named template of the execution with data into file (below output root) and returns an empty string.
+*/
+func (e *Engine) renderTo(name, filename string, data any) (string, error) {
+	if name == "" || filename == "" {
+		return "", errors.New("renderTo needs a template name and a filename")
+	}
+	run := e.running()
+	templ := e.exec.set
+	path, err := e.resolveOutputPath(filename)
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0777)
+	if err != nil {
+		return "", fmt.Errorf("unable to create directory, directory=[%v], error=[%w]", filepath.Dir(path), err)
+	}
+	err = run.startFile(path)
+	if err != nil {
+		return "", err
+	}
+	defer run.endFile(path)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0666)
+	if err != nil {
+		return "", fmt.Errorf("unable to open file, file=[%v], error=[%w]", path, err)
+	}
+	writer := bufio.NewWriter(file)
+	err = templ.ExecuteTemplate(&limitWriter{w: writer, limit: e.maxOutputBytes, run: run}, name, data)
+	if err != nil {
+		file.Close()
+		if e.partialOutput == "delete" {
+			os.Remove(path)
+		}
+		return "", fmt.Errorf("unable to execute template, name=[%v], file=[%v], error=[%w]", name, path, err)
+	}
+	err = writer.Flush()
+	if err != nil {
+		file.Close()
+		return "", fmt.Errorf("unable to flush file, file=[%v], error=[%w]", path, err)
+	}
+	err = file.Close()
+	if err != nil {
+		return "", fmt.Errorf("unable to close file, file=[%v], error=[%w]", path, err)
+	}
+	e.tracker.recordProduced(path)
+	return "", nil
+}
+
+/*
+resolveOutputPath resolves filename relative to output root and rejects paths outside of output root.
+Output root and path are compared after resolving symbolic links (a link below the output root must not
+lead outside of it).
+*/
+func (e *Engine) resolveOutputPath(filename string) (string, error) {
+	if e.outputRoot == "" {
+		return "", fmt.Errorf("writing additional files not possible, output root not set, file=[%v]", filename)
+	}
+	path := filename
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(e.outputRoot, path)
+	}
+	path = filepath.Clean(path)
+	rel, err := filepath.Rel(realPath(e.outputRoot), realPath(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("access denied, file outside of output root, file=[%v], root=[%v]", filename, e.outputRoot)
+	}
+	if rel == "." {
+		return "", fmt.Errorf("access denied, file is output root itself, file=[%v]", filename)
+	}
+	return path, nil
+}
+
+/*
+toTypeHTML avoids autoescaping of HTML string (by html template engine.)
+*/
+func toTypeHTML(s string) template.HTML {
+	return template.HTML(s)
+}
+
+/*
+toTypeCSS avoids autoescaping of CSS string (by html template engine).
+*/
+func toTypeCSS(s string) template.CSS {
+	return template.CSS(s)
+}
+
+/*
+toTypeJS avoids autoescaping of JS string (by html template engine).
+*/
+func toTypeJS(s string) template.JS {
+	return template.JS(s)
+}
+
+/*
+toTypeURL avoids autoescaping of URL string (by html template engine).
+*/
+func toTypeURL(s string) template.URL {
+	return template.URL(s)
+}
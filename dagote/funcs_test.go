@@ -0,0 +1,163 @@
+package dagote
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/*
+TestResolveOutputPath tests that files written by writeFile and renderTo stay below the output root.
+*/
+func TestResolveOutputPath(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(dir, "out")
+	outside := filepath.Join(dir, "outside")
+	for _, d := range []string{filepath.Join(root, "sub"), outside} {
+		if err := os.MkdirAll(d, 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	symlinks := true
+	for link, target := range map[string]string{
+		filepath.Join(root, "escape"):      outside,
+		filepath.Join(root, "inner"):       filepath.Join(root, "sub"),
+		filepath.Join(root, "file.txt"):    filepath.Join(outside, "file.txt"),
+		filepath.Join(dir, "linkedroot"):   root,
+		filepath.Join(root, "sub", "back"): dir,
+	} {
+		if err := os.Symlink(target, link); err != nil {
+			symlinks = false
+		}
+	}
+
+	tests := []struct {
+		name     string
+		root     string
+		filename string
+		want     string // empty: access denied
+		symlink  bool
+	}{
+		{"relative", root, "a.txt", filepath.Join(root, "a.txt"), false},
+		{"subdirectory", root, "sub/new/a.txt", filepath.Join(root, "sub", "new", "a.txt"), false},
+		{"absolute inside", root, filepath.Join(root, "a.txt"), filepath.Join(root, "a.txt"), false},
+		{"dot dot inside", root, "sub/../a.txt", filepath.Join(root, "a.txt"), false},
+		{"dot dot escape", root, "../outside/a.txt", "", false},
+		{"absolute outside", root, filepath.Join(outside, "a.txt"), "", false},
+		{"output root itself", root, ".", "", false},
+		{"symbolic link to outside directory", root, "escape/a.txt", "", true},
+		{"symbolic link to outside file", root, "file.txt", "", true},
+		{"symbolic link to parent", root, "sub/back/outside/a.txt", "", true},
+		{"symbolic link inside", root, "inner/a.txt", filepath.Join(root, "inner", "a.txt"), true},
+		{"symbolic link as output root", filepath.Join(dir, "linkedroot"), "a.txt", filepath.Join(dir, "linkedroot", "a.txt"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.symlink && !symlinks {
+				t.Skip("symbolic links not supported")
+			}
+			engine, err := New(WithOutputRoot(tt.root))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := engine.resolveOutputPath(tt.filename)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("resolveOutputPath(%q) = %q, want access denied", tt.filename, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("resolveOutputPath(%q) = %q, %v, want %q", tt.filename, got, err, tt.want)
+			}
+		})
+	}
+}
+
+/*
+TestParseJSONYAMLShapes tests that JSON and YAML data of any top level shape (object, array, scalar) is accepted.
+*/
+func TestParseJSONYAMLShapes(t *testing.T) {
+	tests := []struct {
+		name      string
+		parse     func([]byte, string) (any, error)
+		data      string
+		want      any
+		wantShape string
+		wantErr   bool
+	}{
+		{"json object", parseJSON, `{"a":1}`, map[string]any{"a": float64(1)}, "object", false},
+		{"json array", parseJSON, `[{"id":1},{"id":2}]`, []any{map[string]any{"id": float64(1)}, map[string]any{"id": float64(2)}}, "array", false},
+		{"json string", parseJSON, `"text"`, "text", "string", false},
+		{"json number", parseJSON, `42`, float64(42), "number", false},
+		{"json null", parseJSON, `null`, nil, "null", false},
+		{"json invalid", parseJSON, `{"a":`, nil, "", true},
+		{"yaml mapping", parseYAML, "a: 1\n", map[string]any{"a": 1}, "object", false},
+		{"yaml sequence", parseYAML, "- a\n- b\n", []any{"a", "b"}, "array", false},
+		{"yaml scalar", parseYAML, "true\n", true, "boolean", false},
+		{"yaml empty", parseYAML, "", map[string]any{}, "object", false},
+		{"yaml invalid", parseYAML, "a: [1\n", nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parse([]byte(tt.data), "test")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parse(%q) error = %v, wantErr %v", tt.data, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parse(%q) = %#v, want %#v", tt.data, got, tt.want)
+			}
+			if shape := jsonShape(got); shape != tt.wantShape {
+				t.Errorf("jsonShape(%#v) = %v, want %v", got, shape, tt.wantShape)
+			}
+		})
+	}
+}
+
+/*
+TestParseStreams tests the decoding of multi-document YAML and NDJSON streams.
+*/
+func TestParseStreams(t *testing.T) {
+	tests := []struct {
+		name    string
+		parse   func(r io.Reader, filename string) ([]any, error)
+		data    string
+		want    []any
+		wantErr string
+	}{
+		{"yaml documents", parseYAMLAll, "a: 1\n---\n- x\n---\ntext\n", []any{map[string]any{"a": 1}, []any{"x"}, "text"}, ""},
+		{"yaml empty documents skipped", parseYAMLAll, "---\n---\na: 1\n---\n", []any{map[string]any{"a": 1}}, ""},
+		{"yaml empty stream", parseYAMLAll, "", []any{}, ""},
+		{"yaml invalid document", parseYAMLAll, "a: 1\n---\nb: [1\n", nil, "document=[2]"},
+		{"ndjson lines", parseNDJSON, "{\"id\":1}\n[2]\n\"s\"\n", []any{map[string]any{"id": float64(1)}, []any{float64(2)}, "s"}, ""},
+		{"ndjson blank lines and crlf", parseNDJSON, "\r\n{\"id\":1}\r\n\n  \n{\"id\":2}", []any{map[string]any{"id": float64(1)}, map[string]any{"id": float64(2)}}, ""},
+		{"ndjson empty stream", parseNDJSON, "", []any{}, ""},
+		{"ndjson invalid line", parseNDJSON, "{\"id\":1}\n\n{\"id\":\n", nil, "line=[3]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parse(strings.NewReader(tt.data), "test")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parse(%q) error = %v, want error containing %q", tt.data, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse(%q) error = %v", tt.data, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parse(%q) = %#v, want %#v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
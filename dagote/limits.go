@@ -0,0 +1,435 @@
+package dagote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+)
+
+// errors of execution limits
+var (
+	ErrTimeout     = errors.New("execution timeout exceeded")
+	ErrOutputLimit = errors.New("maximum output size exceeded")
+	ErrReadLimit   = errors.New("maximum read size exceeded")
+)
+
+/*
+WithTimeout limits the duration of each template execution (0 = no limit).
+*/
+func WithTimeout(timeout time.Duration) Option {
+	return func(e *Engine) error {
+		if timeout < 0 {
+			return fmt.Errorf("timeout must not be negative, timeout=[%v]", timeout)
+		}
+		e.timeout = timeout
+		return nil
+	}
+}
+
+/*
+WithMaxOutputBytes limits the size of each output (start template, writeFile, renderTo) in bytes (0 = no limit).
+*/
+func WithMaxOutputBytes(limit int64) Option {
+	return func(e *Engine) error {
+		if limit < 0 {
+			return fmt.Errorf("maximum output size must not be negative, limit=[%v]", limit)
+		}
+		e.maxOutputBytes = limit
+		return nil
+	}
+}
+
+/*
+WithMaxReadBytes limits the size of each file read by data functions and LoadDotFile in bytes (0 = no limit).
+*/
+func WithMaxReadBytes(limit int64) Option {
+	return func(e *Engine) error {
+		if limit < 0 {
+			return fmt.Errorf("maximum read size must not be negative, limit=[%v]", limit)
+		}
+		e.maxReadBytes = limit
+		return nil
+	}
+}
+
+/*
+WithPartialOutput sets the policy for partially written files of failed executions (delete, keep).
+*/
+func WithPartialOutput(policy string) Option {
+	return func(e *Engine) error {
+		policy = strings.ToLower(policy)
+		if policy != "delete" && policy != "keep" {
+			return fmt.Errorf("partial output policy not supported, policy=[%v]", policy)
+		}
+		e.partialOutput = policy
+		return nil
+	}
+}
+
+/*
+PartialOutput returns the policy for partially written files of failed executions (delete, keep).
+*/
+func (e *Engine) PartialOutput() string {
+	return e.partialOutput
+}
+
+/*
+execution holds the state of one template execution (stopped e.g. by timeout).
+*/
+type execution struct {
+	mu        sync.Mutex
+	stopped   error
+	ctx       context.Context // done when execution is stopped or finished (long running queries)
+	cancel    context.CancelFunc
+	writing   map[string]bool // files in progress (renderTo)
+	templates []string        // parse names of the templates being evaluated (used by executing goroutine only)
+}
+
+/*
+executor is the executable clone of the template set of an engine. Its functions (data functions, 'writeFile',
+'renderTo') are bound once to the executor and use the execution currently running.
+*/
+type executor struct {
+	set templateSet
+	run *execution // current execution (nil: none)
+}
+
+/*
+newExecution creates the state of a template execution.
+*/
+func newExecution() *execution {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &execution{ctx: ctx, cancel: cancel}
+}
+
+/*
+stop stops the execution, all further writes fail with error (waits for a write in progress).
+*/
+func (x *execution) stop(err error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.stopped = err
+	x.cancel()
+}
+
+/*
+context returns the context of the execution (background context if there is no execution).
+*/
+func (x *execution) context() context.Context {
+	if x == nil {
+		return context.Background()
+	}
+	return x.ctx
+}
+
+/*
+err returns the error which stopped the execution (nil: execution running or no execution).
+*/
+func (x *execution) err() error {
+	if x == nil {
+		return nil
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.stopped
+}
+
+/*
+enterTemplate records that evaluation of a template parsed from file (parse name) starts.
+*/
+func (x *execution) enterTemplate(parseName string) {
+	if x != nil {
+		x.templates = append(x.templates, parseName)
+	}
+}
+
+/*
+leaveTemplate records that evaluation of the innermost template ends.
+*/
+func (x *execution) leaveTemplate() {
+	if x != nil && len(x.templates) > 0 {
+		x.templates = x.templates[:len(x.templates)-1]
+	}
+}
+
+/*
+template returns the parse name of the innermost template being evaluated (false: none).
+*/
+func (x *execution) template() (string, bool) {
+	if x == nil || len(x.templates) == 0 {
+		return "", false
+	}
+	return x.templates[len(x.templates)-1], true
+}
+
+/*
+startFile registers file in progress, fails if execution has been stopped.
+*/
+func (x *execution) startFile(path string) error {
+	if x == nil {
+		return nil
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.stopped != nil {
+		return x.stopped
+	}
+	if x.writing == nil {
+		x.writing = make(map[string]bool)
+	}
+	x.writing[path] = true
+	return nil
+}
+
+/*
+endFile unregisters file in progress.
+*/
+func (x *execution) endFile(path string) {
+	if x == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	delete(x.writing, path)
+}
+
+/*
+filesInProgress returns the files in progress.
+*/
+func (x *execution) filesInProgress() []string {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return sortedKeys(x.writing)
+}
+
+/*
+limitWriter enforces the maximum output size and stops writing after the execution has been stopped.
+*/
+type limitWriter struct {
+	w       io.Writer
+	limit   int64 // 0 = no limit
+	written int64
+	run     *execution
+}
+
+/*
+Write writes data up to the output limit (io.Writer interface).
+*/
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if lw.run != nil {
+		lw.run.mu.Lock()
+		defer lw.run.mu.Unlock()
+		if lw.run.stopped != nil {
+			return 0, lw.run.stopped
+		}
+	}
+	if lw.limit > 0 && lw.written+int64(len(p)) > lw.limit {
+		n, err := lw.w.Write(p[:lw.limit-lw.written])
+		lw.written += int64(n)
+		if err != nil {
+			return n, err
+		}
+		return n, fmt.Errorf("%w, limit=[%d bytes]", ErrOutputLimit, lw.limit)
+	}
+	n, err := lw.w.Write(p)
+	lw.written += int64(n)
+	return n, err
+}
+
+/*
+execute executes start template with limits (timeout, output size).
+After a timeout the execution is stopped cooperatively: all further writes and file reads of the
+execution fail, so that the template execution unwinds.
+*/
+func (e *Engine) execute(w io.Writer, data any) error {
+	ex, err := e.executor()
+	if err != nil {
+		return err
+	}
+	run := newExecution()
+	defer run.cancel()
+	ex.run = run
+	lw := &limitWriter{w: w, limit: e.maxOutputBytes, run: run}
+	if e.timeout <= 0 {
+		err := ex.set.Execute(lw, data)
+		ex.run = nil
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- ex.set.Execute(lw, data)
+	}()
+	timer := time.NewTimer(e.timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		ex.run = nil
+		return err
+	case <-timer.C:
+		err := fmt.Errorf("%w, timeout=[%v]", ErrTimeout, e.timeout)
+		run.stop(err)
+		// the stopped execution may still be unwinding, it keeps its executor, the next execution gets a new one
+		e.exec = nil
+		if e.partialOutput == "delete" {
+			for _, path := range run.filesInProgress() {
+				os.Remove(path)
+			}
+		}
+		return err
+	}
+}
+
+/*
+executor returns the executor of the engine. It is created on first execution as clone of the template set
+(which itself is never executed, so that it can be cloned at any time) with the functions bound to it.
+*/
+func (e *Engine) executor() (*executor, error) {
+	if e.exec != nil {
+		return e.exec, nil
+	}
+	var set templateSet
+	var err error
+	switch t := e.templ.(type) {
+	case *texttemplate.Template:
+		set, err = t.Clone()
+	case *htmltemplate.Template:
+		set, err = t.Clone()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to clone template set for execution, error=[%w]", err)
+	}
+	ex := &executor{set: set}
+	x := *e
+	x.exec = ex
+	setFuncs(set, x.executorFuncs())
+	e.exec = ex
+	return ex, nil
+}
+
+/*
+executorFuncs returns the functions using the current execution of the executor (data functions, 'writeFile',
+'renderTo', 'jq', markers of templates being evaluated).
+*/
+func (e *Engine) executorFuncs() map[string]any {
+	funcs := make(map[string]any)
+	for group, groupFuncs := range e.loaderFuncs() {
+		if contains(e.groups, group) {
+			for name, f := range groupFuncs {
+				funcs[name] = f
+			}
+		}
+	}
+	if contains(e.groups, "output") {
+		funcs["writeFile"] = e.WriteFile
+		funcs["renderTo"] = e.renderTo
+	}
+	if contains(e.groups, "query") {
+		funcs["jq"] = e.jq
+		funcs["jqFirst"] = func(query string, data any) (any, error) {
+			return first(e.jq(query, data))
+		}
+	}
+	if e.dataPath == "template" {
+		funcs[enterTemplateFunc] = func(parseName string) bool {
+			e.running().enterTemplate(parseName)
+			return false
+		}
+		funcs[leaveTemplateFunc] = func() bool {
+			e.running().leaveTemplate()
+			return false
+		}
+	}
+	return funcs
+}
+
+/*
+jq runs jq query on data (see JQ), a long running query is canceled when the execution is stopped.
+*/
+func (e *Engine) jq(query string, data any) ([]any, error) {
+	run := e.running()
+	results, err := runJQ(run.context(), query, data)
+	if stopped := run.err(); stopped != nil {
+		return nil, stopped
+	}
+	return results, err
+}
+
+/*
+running returns the current execution of the engine (nil: none).
+*/
+func (e *Engine) running() *execution {
+	if e.exec == nil {
+		return nil
+	}
+	return e.exec.run
+}
+
+/*
+stopReader fails reading after the execution has been stopped.
+*/
+type stopReader struct {
+	io.ReadCloser
+	run *execution
+}
+
+/*
+Read reads from file as long as the execution is running.
+*/
+func (r *stopReader) Read(p []byte) (int, error) {
+	if err := r.run.err(); err != nil {
+		return 0, err
+	}
+	return r.ReadCloser.Read(p)
+}
+
+/*
+limitReader fails when more than limit bytes are read from file (streaming counterpart of readLimited).
+*/
+type limitReader struct {
+	io.ReadCloser
+	remaining int64
+	limit     int64
+	filename  string
+}
+
+/*
+Read reads from file up to the read limit.
+*/
+func (r *limitReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.ReadCloser.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return 0, fmt.Errorf("%w, file=[%v], limit=[%d bytes]", ErrReadLimit, r.filename, r.limit)
+	}
+	return n, err
+}
+
+/*
+openLocal opens file of local file system.
+*/
+func openLocal(filename string) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return os.Open(filename)
+	}
+}
+
+/*
+openFS opens file of file system.
+*/
+func openFS(fsys fs.FS, name string) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return fsys.Open(name)
+	}
+}
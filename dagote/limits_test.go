@@ -0,0 +1,288 @@
+package dagote
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+TestLimitWriter tests the enforcement of the maximum output size.
+*/
+func TestLimitWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int64
+		writes  []string
+		want    string
+		wantErr error
+	}{
+		{"no limit", 0, []string{"abc", "def"}, "abcdef", nil},
+		{"below limit", 10, []string{"abc", "def"}, "abcdef", nil},
+		{"exact limit", 6, []string{"abc", "def"}, "abcdef", nil},
+		{"limit exceeded", 5, []string{"abc", "def"}, "abcde", ErrOutputLimit},
+		{"limit exceeded by first write", 2, []string{"abc"}, "ab", ErrOutputLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			lw := &limitWriter{w: &out, limit: tt.limit}
+			var err error
+			for _, s := range tt.writes {
+				if _, err = lw.Write([]byte(s)); err != nil {
+					break
+				}
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+/*
+TestLimitWriterStopped tests that writes of a stopped execution fail.
+*/
+func TestLimitWriterStopped(t *testing.T) {
+	var out bytes.Buffer
+	run := newExecution()
+	lw := &limitWriter{w: &out, run: run}
+	if _, err := lw.Write([]byte("abc")); err != nil {
+		t.Fatalf("write of running execution failed: %v", err)
+	}
+	run.stop(ErrTimeout)
+	if _, err := lw.Write([]byte("def")); !errors.Is(err, ErrTimeout) {
+		t.Errorf("write of stopped execution: error = %v, want %v", err, ErrTimeout)
+	}
+	if out.String() != "abc" {
+		t.Errorf("output = %q, want %q", out.String(), "abc")
+	}
+}
+
+/*
+TestExecuteLimits tests the limits of template executions (output size, read size).
+*/
+func TestExecuteLimits(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small.txt")
+	large := filepath.Join(dir, "large.json")
+	if err := os.WriteFile(small, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(large, []byte(`{"text": "`+strings.Repeat("x", 100)+`"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		options []Option
+		want    string
+		wantErr error
+	}{
+		{"output below limit", "{{ repeat 10 \"x\" }}", []Option{WithMaxOutputBytes(10)}, strings.Repeat("x", 10), nil},
+		{"output limit exceeded", "{{ repeat 11 \"x\" }}", []Option{WithMaxOutputBytes(10)}, strings.Repeat("x", 10), ErrOutputLimit},
+		{"read below limit", fmt.Sprintf("{{ readText %q }}", small), []Option{WithMaxReadBytes(5)}, "hello", nil},
+		{"read limit exceeded", fmt.Sprintf("{{ readText %q }}", small), []Option{WithMaxReadBytes(4)}, "", ErrReadLimit},
+		{"parsed read limit exceeded", fmt.Sprintf("{{ (readJSON %q).text }}", large), []Option{WithMaxReadBytes(50)}, "", ErrReadLimit},
+		{"file exists ignores read limit", fmt.Sprintf("{{ fileExists %q }}", large), []Option{WithMaxReadBytes(1)}, "true", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := New(tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := engine.Parse("test", tt.text); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			err = engine.Execute(&out)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+/*
+TestReadLimitCacheHit tests that the read limit is enforced for data cached by an engine without limit.
+*/
+func TestReadLimitCacheHit(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.json")
+	if err := os.WriteFile(filename, []byte(`{"a": "abcdefghij"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	text := fmt.Sprintf("{{ (readJSON %q).a }}", filename)
+	cache := NewDataCache()
+
+	tests := []struct {
+		name    string
+		limit   int64
+		want    string
+		wantErr error
+	}{
+		{"fills cache", 0, "abcdefghij", nil},
+		{"cache hit within limit", 100, "abcdefghij", nil},
+		{"cache hit exceeds limit", 5, "", ErrReadLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := New(WithDataCache(cache), WithMaxReadBytes(tt.limit))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := engine.Parse("test", text); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			err = engine.Execute(&out)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+/*
+countingWriter counts the bytes written (safe for concurrent use).
+*/
+type countingWriter struct {
+	mu sync.Mutex
+	n  int
+}
+
+/*
+Write counts the bytes written (io.Writer interface).
+*/
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.n += len(p)
+	return len(p), nil
+}
+
+/*
+written returns the number of bytes written.
+*/
+func (w *countingWriter) written() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.n
+}
+
+/*
+TestTimeoutStopsExecution tests that executions exceeding the timeout are stopped cooperatively
+(by writes, data functions and queries) and don't keep running in the background.
+*/
+func TestTimeoutStopsExecution(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(filename, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"writing", "{{ range until 100000 }}{{ range until 100000 }}x{{ end }}{{ end }}"},
+		{"reading", fmt.Sprintf("{{ range until 100000 }}{{ range until 100000 }}{{ $data := readText %q }}{{ end }}{{ end }}", filename)},
+		{"querying", `{{ $results := jq "repeat(1)" . }}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goroutines := runtime.NumGoroutine()
+			engine, err := New(WithTimeout(50 * time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := engine.Parse("test", tt.text); err != nil {
+				t.Fatal(err)
+			}
+			out := &countingWriter{}
+			err = engine.Execute(out)
+			if !errors.Is(err, ErrTimeout) {
+				t.Fatalf("error = %v, want %v", err, ErrTimeout)
+			}
+
+			// executing goroutine must terminate
+			deadline := time.Now().Add(5 * time.Second)
+			for runtime.NumGoroutine() > goroutines {
+				if time.Now().After(deadline) {
+					t.Fatalf("execution still running after timeout, goroutines=[%d], before=[%d]", runtime.NumGoroutine(), goroutines)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			written := out.written()
+			time.Sleep(50 * time.Millisecond)
+			if out.written() != written {
+				t.Errorf("output written after timeout, before=[%d], after=[%d]", written, out.written())
+			}
+		})
+	}
+}
+
+/*
+TestExecutorReused tests that all executions of an engine share one executor (template set cloned and
+functions bound once), and that an execution stopped by timeout leaves its executor behind.
+*/
+func TestExecutorReused(t *testing.T) {
+	for _, format := range []string{"text", "html"} {
+		t.Run(format, func(t *testing.T) {
+			engine, err := New(WithFormat(format), WithTimeout(time.Second))
+			if err != nil {
+				t.Fatal(err)
+			}
+			text := `{{ if .loop }}{{ range until 100000 }}{{ range until 100000 }}x{{ end }}{{ end }}{{ end }}<p>{{ .name }}</p>`
+			if err := engine.Parse("test", text); err != nil {
+				t.Fatal(err)
+			}
+			var executors []*executor
+			for _, name := range []string{"a", "b"} {
+				var out bytes.Buffer
+				if err := engine.ExecuteData(&out, map[string]any{"name": name}); err != nil {
+					t.Fatal(err)
+				}
+				if want := "<p>" + name + "</p>"; out.String() != want {
+					t.Errorf("output = %q, want %q", out.String(), want)
+				}
+				executors = append(executors, engine.exec)
+			}
+			if executors[0] == nil || executors[0] != executors[1] {
+				t.Errorf("executions don't share executor")
+			}
+
+			engine.timeout = 50 * time.Millisecond
+			err = engine.ExecuteData(&countingWriter{}, map[string]any{"loop": true})
+			if !errors.Is(err, ErrTimeout) {
+				t.Fatalf("error = %v, want %v", err, ErrTimeout)
+			}
+			var out bytes.Buffer
+			if err := engine.ExecuteData(&out, map[string]any{"name": "c"}); err != nil {
+				t.Fatal(err)
+			}
+			if engine.exec == executors[0] {
+				t.Errorf("execution after timeout uses executor of stopped execution")
+			}
+			if out.String() != "<p>c</p>" {
+				t.Errorf("output = %q, want %q", out.String(), "<p>c</p>")
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package dagote
+
+import (
+	"fmt"
+	"reflect"
+)
+
+/*
+MergeData deep-merges 'src' into 'dst' and returns the result ('src' takes precedence).
+Maps are merged key by key. Lists are merged according to strategy (replace, append, key).
+All other values of 'dst' are replaced by the values of 'src'. Replacing a top level map by a value
+which isn't a map (e.g. a list) fails, as all data of 'dst' would be lost.
+*/
+func MergeData(dst, src any, strategy, key string) (any, error) {
+	_, dstIsMap := ToMap(dst)
+	_, srcIsMap := ToMap(src)
+	if dstIsMap && !srcIsMap {
+		return nil, fmt.Errorf("unable to merge data, top level map would be replaced by value which is not a map, type=[%T]", src)
+	}
+	return mergeData(dst, src, strategy, key), nil
+}
+
+/*
+mergeData deep-merges 'src' into 'dst' and returns the result (see MergeData).
+*/
+func mergeData(dst, src any, strategy, key string) any {
+	dstMap, dstIsMap := ToMap(dst)
+	srcMap, srcIsMap := ToMap(src)
+	if dstIsMap && srcIsMap {
+		result := make(map[string]any, len(dstMap)+len(srcMap))
+		for k, v := range dstMap {
+			result[k] = v
+		}
+		for k, v := range srcMap {
+			if existing, ok := result[k]; ok {
+				result[k] = mergeData(existing, v, strategy, key)
+			} else {
+				result[k] = v
+			}
+		}
+		return result
+	}
+
+	dstList, dstIsList := ToList(dst)
+	srcList, srcIsList := ToList(src)
+	if dstIsList && srcIsList {
+		switch strategy {
+		case "append":
+			result := make([]any, 0, len(dstList)+len(srcList))
+			result = append(result, dstList...)
+			return append(result, srcList...)
+		case "key":
+			return mergeListByKey(dstList, srcList, strategy, key)
+		}
+	}
+
+	return src
+}
+
+/*
+mergeListByKey merges list elements (maps) with same key value, all other elements are appended.
+*/
+func mergeListByKey(dstList, srcList []any, strategy, key string) []any {
+	result := make([]any, 0, len(dstList)+len(srcList))
+	result = append(result, dstList...)
+
+	// index of dst elements by key value
+	index := make(map[string]int)
+	for i, element := range result {
+		if keyValue, ok := listKeyValue(element, key); ok {
+			if _, exists := index[keyValue]; !exists {
+				index[keyValue] = i
+			}
+		}
+	}
+
+	for _, element := range srcList {
+		keyValue, ok := listKeyValue(element, key)
+		if ok {
+			if i, exists := index[keyValue]; exists {
+				result[i] = mergeData(result[i], element, strategy, key)
+				continue
+			}
+			index[keyValue] = len(result)
+		}
+		result = append(result, element)
+	}
+
+	return result
+}
+
+/*
+listKeyValue returns the string representation of the key value of a list element (map).
+*/
+func listKeyValue(element any, key string) (string, bool) {
+	elementMap, ok := ToMap(element)
+	if !ok {
+		return "", false
+	}
+	value, ok := elementMap[key]
+	if !ok || value == nil {
+		return "", false
+	}
+	return fmt.Sprintf("%v", value), true
+}
+
+/*
+ToMap converts any map with string keys (e.g. map[string]string) to map of any.
+*/
+func ToMap(value any) (map[string]any, bool) {
+	if m, ok := value.(map[string]any); ok {
+		return m, true
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	m := make(map[string]any, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		m[iter.Key().String()] = iter.Value().Interface()
+	}
+	return m, true
+}
+
+/*
+ToList converts any slice or array (e.g. []string) to slice of any.
+*/
+func ToList(value any) ([]any, bool) {
+	if l, ok := value.([]any); ok {
+		return l, true
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	// a slice of bytes is considered as scalar value
+	if rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	l := make([]any, rv.Len())
+	for i := range l {
+		l[i] = rv.Index(i).Interface()
+	}
+	return l, true
+}
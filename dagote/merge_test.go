@@ -0,0 +1,101 @@
+package dagote
+
+import (
+	"reflect"
+	"testing"
+)
+
+/*
+TestMergeData tests the deep merge of dot data (list strategies replace, append and key).
+*/
+func TestMergeData(t *testing.T) {
+	dst := map[string]any{
+		"name":  "base",
+		"debug": false,
+		"db":    map[string]any{"host": "localhost", "port": 5432},
+		"tags":  []any{"a", "b"},
+		"users": []any{
+			map[string]any{"id": 1, "name": "ann", "role": "user"},
+			map[string]any{"id": 2, "name": "bob"},
+		},
+	}
+	src := map[string]any{
+		"debug": true,
+		"db":    map[string]any{"host": "db.example.com"},
+		"tags":  []any{"c"},
+		"users": []any{
+			map[string]any{"id": 1, "role": "admin"},
+			map[string]any{"id": 3, "name": "cid"},
+		},
+	}
+	tests := []struct {
+		name     string
+		dst      any
+		src      any
+		strategy string
+		want     any
+		wantErr  bool
+	}{
+		{
+			"replace", dst, src, "replace",
+			map[string]any{
+				"name":  "base",
+				"debug": true,
+				"db":    map[string]any{"host": "db.example.com", "port": 5432},
+				"tags":  []any{"c"},
+				"users": []any{
+					map[string]any{"id": 1, "role": "admin"},
+					map[string]any{"id": 3, "name": "cid"},
+				},
+			},
+			false,
+		},
+		{
+			"append", dst, src, "append",
+			map[string]any{
+				"name":  "base",
+				"debug": true,
+				"db":    map[string]any{"host": "db.example.com", "port": 5432},
+				"tags":  []any{"a", "b", "c"},
+				"users": []any{
+					map[string]any{"id": 1, "name": "ann", "role": "user"},
+					map[string]any{"id": 2, "name": "bob"},
+					map[string]any{"id": 1, "role": "admin"},
+					map[string]any{"id": 3, "name": "cid"},
+				},
+			},
+			false,
+		},
+		{
+			"key", dst, src, "key",
+			map[string]any{
+				"name":  "base",
+				"debug": true,
+				"db":    map[string]any{"host": "db.example.com", "port": 5432},
+				"tags":  []any{"a", "b", "c"},
+				"users": []any{
+					map[string]any{"id": 1, "name": "ann", "role": "admin"},
+					map[string]any{"id": 2, "name": "bob"},
+					map[string]any{"id": 3, "name": "cid"},
+				},
+			},
+			false,
+		},
+		{"top level lists", []any{1, 2}, []any{3}, "append", []any{1, 2, 3}, false},
+		{"top level scalar replaced", "a", "b", "replace", "b", false},
+		{"nested map replaced by scalar", map[string]any{"db": map[string]any{"host": "x"}}, map[string]any{"db": "none"}, "replace", map[string]any{"db": "none"}, false},
+		{"top level map replaced by list", dst, []any{1}, "replace", nil, true},
+		{"top level map replaced by scalar", dst, "text", "key", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergeData(tt.dst, tt.src, tt.strategy, "id")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MergeData() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeData() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
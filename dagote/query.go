@@ -0,0 +1,229 @@
+package dagote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/itchyny/gojq"
+	"github.com/ohler55/ojg/jp"
+)
+
+// compiled queries (shared by all engines, queries are immutable)
+var (
+	jqCache       sync.Map // query string -> *gojq.Code
+	jsonpathCache sync.Map // expression string -> jp.Expr
+)
+
+/*
+queryFuncs returns the functions of group 'query'.
+*/
+func queryFuncs() map[string]any {
+	return map[string]any{
+		"jq":            JQ,
+		"jqFirst":       JQFirst,
+		"jsonpath":      JSONPath,
+		"jsonpathFirst": JSONPathFirst,
+		"xpath":         XPath,
+		"xpathAll":      XPathAll,
+	}
+}
+
+/*
+JQ runs jq query on data and returns all results (e.g. jq ".items[] | select(.active)" $data).
+*/
+func JQ(query string, data any) ([]any, error) {
+	return runJQ(context.Background(), query, data)
+}
+
+/*
+runJQ runs jq query on data until all results are produced or the context is done (e.g. execution stopped by timeout).
+*/
+func runJQ(ctx context.Context, query string, data any) ([]any, error) {
+	code, err := compileJQ(query)
+	if err != nil {
+		return nil, err
+	}
+	results := []any{}
+	iter := code.RunWithContext(ctx, NormalizeData(data))
+	for {
+		value, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, isErr := value.(error); isErr {
+			return nil, fmt.Errorf("unable to run jq query, query=[%v], error=[%w]", query, err)
+		}
+		results = append(results, value)
+	}
+	return results, nil
+}
+
+/*
+JQFirst runs jq query on data and returns first result (nil if query has no result).
+*/
+func JQFirst(query string, data any) (any, error) {
+	return first(JQ(query, data))
+}
+
+/*
+first returns first result of query (nil if query has no result).
+*/
+func first(results []any, err error) (any, error) {
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+/*
+compileJQ parses and compiles jq query (cached).
+*/
+func compileJQ(query string) (*gojq.Code, error) {
+	if cached, ok := jqCache.Load(query); ok {
+		return cached.(*gojq.Code), nil
+	}
+	parsed, err := gojq.Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse jq query, query=[%v], error=[%w]", query, err)
+	}
+	code, err := gojq.Compile(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile jq query, query=[%v], error=[%w]", query, err)
+	}
+	jqCache.Store(query, code)
+	return code, nil
+}
+
+/*
+JSONPath evaluates JSONPath expression on data and returns all matches (e.g. jsonpath "$.store.book[*].author" $data).
+*/
+func JSONPath(expression string, data any) ([]any, error) {
+	expr, err := compileJSONPath(expression)
+	if err != nil {
+		return nil, err
+	}
+	results := expr.Get(NormalizeData(data))
+	if results == nil {
+		results = []any{}
+	}
+	return results, nil
+}
+
+/*
+JSONPathFirst evaluates JSONPath expression on data and returns first match (nil if nothing matches).
+*/
+func JSONPathFirst(expression string, data any) (any, error) {
+	results, err := JSONPath(expression, data)
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+/*
+compileJSONPath parses JSONPath expression (cached).
+*/
+func compileJSONPath(expression string) (jp.Expr, error) {
+	if expression == "" {
+		return nil, errors.New("jsonpath needs an expression")
+	}
+	if cached, ok := jsonpathCache.Load(expression); ok {
+		return cached.(jp.Expr), nil
+	}
+	expr, err := jp.ParseString(expression)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse JSONPath expression, expression=[%v], error=[%w]", expression, err)
+	}
+	jsonpathCache.Store(expression, expr)
+	return expr, nil
+}
+
+/*
+NormalizeData converts data of the loaders (e.g. []map[string]string, [][]string, int64, time.Time)
+into the generic JSON representation (map[string]any, []any, string, float64, int, bool, nil).
+*/
+func NormalizeData(data any) any {
+	switch value := data.(type) {
+	case nil, string, bool, int, float64:
+		return value
+	case map[string]any:
+		result := make(map[string]any, len(value))
+		for k, v := range value {
+			result[k] = NormalizeData(v)
+		}
+		return result
+	case []any:
+		result := make([]any, len(value))
+		for i, v := range value {
+			result[i] = NormalizeData(v)
+		}
+		return result
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return NormalizeData(i)
+		}
+		f, _ := value.Float64()
+		return f
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	case []byte:
+		return string(value)
+	case *XMLNode:
+		return NormalizeData(value.Map())
+	case fmt.Stringer:
+		// e.g. TOML local date / time
+		if reflect.ValueOf(value).Kind() == reflect.Struct {
+			return value.String()
+		}
+	}
+
+	v := reflect.ValueOf(data)
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return NormalizeData(v.Elem().Interface())
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Map:
+		result := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			result[fmt.Sprint(iter.Key().Interface())] = NormalizeData(iter.Value().Interface())
+		}
+		return result
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return []any{}
+		}
+		result := make([]any, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			result[i] = NormalizeData(v.Index(i).Interface())
+		}
+		return result
+	case reflect.Struct:
+		// e.g. FileInfo of fileStat is not a generic value, marshal via JSON
+		raw, err := json.Marshal(data)
+		if err == nil {
+			var generic any
+			if json.Unmarshal(raw, &generic) == nil {
+				return NormalizeData(generic)
+			}
+		}
+	}
+	return fmt.Sprint(data)
+}
@@ -0,0 +1,133 @@
+package dagote
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+/*
+WithAllowRead restricts the data functions (readXXX, fileXXX) to files below the readable directories:
+the directories of all parsed templates, the directories of dot files, the base directory of the data functions
+(current working directory for data path mode cwd, see WithDataPath) and the given directories.
+*/
+func WithAllowRead(dirs ...string) Option {
+	return func(e *Engine) error {
+		e.sandbox = true
+		for _, dir := range dirs {
+			dir = strings.TrimSpace(dir)
+			if dir != "" {
+				e.tracker.addReadRoot(dir)
+			}
+		}
+		return nil
+	}
+}
+
+/*
+ReadRoots returns the readable directories (empty if file access is not restricted).
+*/
+func (e *Engine) ReadRoots() []string {
+	if !e.sandbox {
+		return nil
+	}
+	roots := e.tracker.readRoots()
+	base := ""
+	switch e.dataPath {
+	case "cwd":
+		base = "."
+	case "datadir":
+		base = e.dataDir
+	}
+	if base != "" && !contains(roots, base) {
+		roots = append([]string{base}, roots...)
+	}
+	return roots
+}
+
+/*
+addReadRoot adds directory (of template or dot file) to the readable directories.
+*/
+func (e *Engine) addReadRoot(dir string) {
+	if e.sandbox && dir != "" {
+		e.tracker.addReadRoot(dir)
+	}
+}
+
+/*
+checkRead checks whether file is below one of the readable directories.
+*/
+func (e *Engine) checkRead(filename string) error {
+	if !e.sandbox {
+		return nil
+	}
+	roots := e.ReadRoots()
+	for _, root := range roots {
+		if e.isBelow(root, filename) {
+			return nil
+		}
+	}
+	return fmt.Errorf("access denied, file outside of readable directories, file=[%v], readable=[%v]", filename, strings.Join(roots, ", "))
+}
+
+/*
+isBelow checks whether file is below (or equal to) directory root.
+Paths of the local file system are compared after resolving symbolic links.
+*/
+func (e *Engine) isBelow(root, filename string) bool {
+	if e.fsys != nil {
+		name, err := fsPath(filename)
+		if err != nil {
+			return false
+		}
+		dir := path.Clean(filepath.ToSlash(root))
+		return dir == "." || name == dir || strings.HasPrefix(name, dir+"/")
+	}
+	dir := realPath(root)
+	name := realPath(filename)
+	rel, err := filepath.Rel(dir, name)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+/*
+realPath returns the absolute path with symbolic links resolved (as far as the path exists).
+*/
+func realPath(name string) string {
+	absPath, err := filepath.Abs(name)
+	if err != nil {
+		return name
+	}
+	return resolveLinks(absPath, 0)
+}
+
+// maxLinks is the maximum number of dangling symbolic links followed by resolveLinks (link cycles)
+const maxLinks = 40
+
+/*
+resolveLinks resolves the symbolic links of the existing part of absolute path. Dangling links (e.g. to a file
+which is created when writing) are resolved to their target.
+*/
+func resolveLinks(absPath string, links int) string {
+	// resolve existing part of path (the file itself may not exist)
+	rest := ""
+	for current := absPath; ; current = filepath.Dir(current) {
+		if resolved, err := filepath.EvalSymlinks(current); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		if target, err := os.Readlink(current); err == nil && links < maxLinks {
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(current), target)
+			}
+			return resolveLinks(filepath.Join(target, rest), links+1)
+		}
+		if filepath.Dir(current) == current {
+			return absPath
+		}
+		rest = filepath.Join(filepath.Base(current), rest)
+	}
+}
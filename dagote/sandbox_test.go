@@ -0,0 +1,144 @@
+package dagote
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+/*
+TestRealPath tests the resolution of symbolic links of existing and non-existing paths.
+*/
+func TestRealPath(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "target")
+	if err := os.Mkdir(target, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symbolic links not supported: %v", err)
+	}
+	dangling := filepath.Join(dir, "dangling")
+	if err := os.Symlink(filepath.Join("target", "new.json"), dangling); err != nil {
+		t.Fatal(err)
+	}
+	cycle := filepath.Join(dir, "cycle")
+	if err := os.Symlink(cycle, cycle); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"existing directory", target, target},
+		{"symbolic link", link, target},
+		{"file below link", filepath.Join(link, "file.json"), filepath.Join(target, "file.json")},
+		{"missing path below link", filepath.Join(link, "a", "b.json"), filepath.Join(target, "a", "b.json")},
+		{"dot dot", filepath.Join(link, "..", "target"), target},
+		{"dangling link", dangling, filepath.Join(target, "new.json")},
+		{"path below dangling link", filepath.Join(dangling, "x"), filepath.Join(target, "new.json", "x")},
+		{"link cycle", cycle, cycle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := realPath(tt.path); got != tt.want {
+				t.Errorf("realPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+/*
+TestCheckRead tests the restriction of data functions to the readable directories (local file system).
+*/
+func TestCheckRead(t *testing.T) {
+	dir := t.TempDir()
+	allowed := filepath.Join(dir, "allowed")
+	secret := filepath.Join(dir, "secret")
+	for _, d := range []string{allowed, secret, allowed + "-sibling"} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	symlinks := true
+	if err := os.Symlink(secret, filepath.Join(allowed, "escape")); err != nil {
+		symlinks = false
+	}
+	engine, err := New(WithDataPath("datadir", allowed), WithAllowRead())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		allowed  bool
+		symlink  bool
+	}{
+		{"file in directory", filepath.Join(allowed, "data.json"), true, false},
+		{"file in subdirectory", filepath.Join(allowed, "sub", "data.json"), true, false},
+		{"directory itself", allowed, true, false},
+		{"dot dot inside", filepath.Join(allowed, "sub", "..", "data.json"), true, false},
+		{"dot dot escape", filepath.Join(allowed, "..", "secret", "data.json"), false, false},
+		{"parent directory", dir, false, false},
+		{"sibling with same prefix", filepath.Join(allowed+"-sibling", "data.json"), false, false},
+		{"symbolic link escape", filepath.Join(allowed, "escape", "data.json"), false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.symlink && !symlinks {
+				t.Skip("symbolic links not supported")
+			}
+			err := engine.checkRead(tt.filename)
+			if tt.allowed && err != nil {
+				t.Errorf("checkRead(%q) = %v, want access", tt.filename, err)
+			}
+			if !tt.allowed && err == nil {
+				t.Errorf("checkRead(%q) = nil, want access denied", tt.filename)
+			}
+		})
+	}
+}
+
+/*
+TestCheckReadFS tests the restriction of data functions to the readable directories (engine file system).
+*/
+func TestCheckReadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"data/a.json":   {Data: []byte(`{}`)},
+		"secret/s.json": {Data: []byte(`{}`)},
+	}
+	engine, err := New(WithFS(fsys), WithDataPath("datadir", "data"), WithAllowRead())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		filename string
+		allowed  bool
+	}{
+		{"data/a.json", true},
+		{"data/sub/../a.json", true},
+		{"data/../secret/s.json", false},
+		{"secret/s.json", false},
+		{"../data/a.json", false},
+		{"/data/a.json", false},
+		{"database/a.json", false},
+	}
+	for _, tt := range tests {
+		err := engine.checkRead(tt.filename)
+		if tt.allowed && err != nil {
+			t.Errorf("checkRead(%q) = %v, want access", tt.filename, err)
+		}
+		if !tt.allowed && err == nil {
+			t.Errorf("checkRead(%q) = nil, want access denied", tt.filename)
+		}
+	}
+}
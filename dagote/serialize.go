@@ -0,0 +1,253 @@
+package dagote
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	xml "github.com/clbanning/mxj/v2"
+	toml "github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// xmlEscaper escapes the special characters of XML values (like mxj with XMLEscapeChars enabled)
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;")
+
+/*
+encodeFuncs returns the functions of group 'encode'.
+*/
+func encodeFuncs() map[string]any {
+	return map[string]any{
+		"toJSON":       ToJSON,
+		"toPrettyJSON": ToPrettyJSON,
+		"toYAML":       ToYAML,
+		"toTOML":       ToTOML,
+		"toCSV":        ToCSV,
+		"toXML":        ToXML,
+	}
+}
+
+/*
+ToJSON serializes data to compact JSON (html characters are not escaped).
+*/
+func ToJSON(data any) (string, error) {
+	return encodeJSON(data, "")
+}
+
+/*
+ToPrettyJSON serializes data to indented JSON (html characters are not escaped).
+*/
+func ToPrettyJSON(data any) (string, error) {
+	return encodeJSON(data, "  ")
+}
+
+/*
+encodeJSON serializes data to JSON with given indent (empty: compact).
+*/
+func encodeJSON(data any, indent string) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	err := encoder.Encode(plainData(data))
+	if err != nil {
+		return "", fmt.Errorf("unable to serialize data to JSON, error=[%w]", err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+/*
+ToYAML serializes data to YAML (indent: 2 spaces).
+*/
+func ToYAML(data any) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	err := encoder.Encode(plainData(data))
+	if err != nil {
+		return "", fmt.Errorf("unable to serialize data to YAML, error=[%w]", err)
+	}
+	err = encoder.Close()
+	if err != nil {
+		return "", fmt.Errorf("unable to serialize data to YAML, error=[%w]", err)
+	}
+	return buf.String(), nil
+}
+
+/*
+ToTOML serializes data (map) to TOML.
+*/
+func ToTOML(data any) (string, error) {
+	m, ok := ToMap(plainData(data))
+	if !ok {
+		return "", fmt.Errorf("unable to serialize data to TOML, data must be a map, type=[%T]", data)
+	}
+	var buf bytes.Buffer
+	err := toml.NewEncoder(&buf).Encode(m)
+	if err != nil {
+		return "", fmt.Errorf("unable to serialize data to TOML, error=[%w]", err)
+	}
+	return buf.String(), nil
+}
+
+/*
+ToCSV serializes data to CSV. Data is a list of rows ([][]string, [][]any) or a list of records (maps).
+For records the header defines the columns and their order (default: all keys, sorted).
+For rows a given header is written as first row.
+*/
+func ToCSV(data any, header ...any) (string, error) {
+	var columns []string
+	for _, h := range header {
+		if list, ok := ToList(h); ok {
+			for _, column := range list {
+				columns = append(columns, valueString(column))
+			}
+			continue
+		}
+		columns = append(columns, valueString(h))
+	}
+	rows, err := csvRows(data, columns)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	err = writer.WriteAll(rows)
+	if err != nil {
+		return "", fmt.Errorf("unable to serialize data to CSV, error=[%w]", err)
+	}
+	return buf.String(), nil
+}
+
+/*
+csvRows converts list of rows or list of records into CSV rows (including header).
+*/
+func csvRows(data any, columns []string) ([][]string, error) {
+	if rows, ok := data.([][]string); ok {
+		if len(columns) > 0 {
+			return append([][]string{columns}, rows...), nil
+		}
+		return rows, nil
+	}
+	list, ok := ToList(plainData(data))
+	if !ok {
+		return nil, fmt.Errorf("unable to serialize data to CSV, data must be a list of rows or records, type=[%T]", data)
+	}
+
+	// list of records (maps)
+	if len(list) > 0 {
+		if _, isMap := ToMap(list[0]); isMap {
+			if len(columns) == 0 {
+				columns = recordKeys(list)
+			}
+			rows := [][]string{columns}
+			for i, element := range list {
+				record, ok := ToMap(element)
+				if !ok {
+					return nil, fmt.Errorf("unable to serialize data to CSV, element is not a record, element=[%d], type=[%T]", i+1, element)
+				}
+				row := make([]string, len(columns))
+				for j, column := range columns {
+					row[j] = valueString(record[column])
+				}
+				rows = append(rows, row)
+			}
+			return rows, nil
+		}
+	}
+
+	// list of rows (lists)
+	var rows [][]string
+	if len(columns) > 0 {
+		rows = append(rows, columns)
+	}
+	for i, element := range list {
+		fields, ok := ToList(element)
+		if !ok {
+			return nil, fmt.Errorf("unable to serialize data to CSV, element is not a row, element=[%d], type=[%T]", i+1, element)
+		}
+		row := make([]string, len(fields))
+		for j, field := range fields {
+			row[j] = valueString(field)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+/*
+recordKeys returns the sorted union of the keys of all records.
+*/
+func recordKeys(records []any) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, element := range records {
+		record, _ := ToMap(element)
+		for key := range record {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/*
+ToXML serializes data (map) to indented XML with given root element name (default: 'doc').
+Keys with prefix '-' are written as attributes, key '#text' as element text (like readXML).
+*/
+func ToXML(data any, root ...string) (string, error) {
+	m, ok := escapeXMLValues(NormalizeData(data)).(map[string]any)
+	if !ok {
+		return "", fmt.Errorf("unable to serialize data to XML, data must be a map, type=[%T]", data)
+	}
+	if len(root) > 1 {
+		return "", errors.New("toXML accepts only one root element name")
+	}
+	xmlRaw, err := xml.Map(m).XmlIndent("", "  ", root...)
+	if err != nil {
+		return "", fmt.Errorf("unable to serialize data to XML, error=[%w]", err)
+	}
+	return string(xmlRaw), nil
+}
+
+/*
+escapeXMLValues returns copy of normalized data with special characters (<, >, &, ', ") of all string values escaped.
+Escaping is done here (instead of mxj's process-wide XMLEscapeChars setting) to leave other users of mxj unaffected.
+*/
+func escapeXMLValues(data any) any {
+	switch value := data.(type) {
+	case string:
+		return xmlEscaper.Replace(value)
+	case map[string]any:
+		result := make(map[string]any, len(value))
+		for k, v := range value {
+			result[k] = escapeXMLValues(v)
+		}
+		return result
+	case []any:
+		result := make([]any, len(value))
+		for i, v := range value {
+			result[i] = escapeXMLValues(v)
+		}
+		return result
+	}
+	return data
+}
+
+/*
+plainData converts queryable XML nodes into maps, all other data is returned unchanged.
+*/
+func plainData(data any) any {
+	if node, ok := data.(*XMLNode); ok {
+		return node.Map()
+	}
+	return data
+}
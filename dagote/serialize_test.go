@@ -0,0 +1,42 @@
+package dagote
+
+import "testing"
+
+/*
+TestToCSV tests the serialization of rows and records to CSV (column order of header).
+*/
+func TestToCSV(t *testing.T) {
+	records := []any{
+		map[string]any{"name": "ann", "age": 30, "city": "Bonn"},
+		map[string]any{"name": "bob", "zip": "53111"},
+	}
+	tests := []struct {
+		name    string
+		data    any
+		header  []any
+		want    string
+		wantErr bool
+	}{
+		{"records with sorted keys", records, nil, "age,city,name,zip\n30,Bonn,ann,\n,,bob,53111\n", false},
+		{"records with header order", records, []any{"name", "age"}, "name,age\nann,30\nbob,\n", false},
+		{"records with header list", records, []any{[]any{"zip", "name"}}, "zip,name\n,ann\n53111,bob\n", false},
+		{"records with string list header", records, []any{[]string{"city", "name"}}, "city,name\nBonn,ann\n,bob\n", false},
+		{"csv map records", []map[string]string{{"b": "2", "a": "1"}}, nil, "a,b\n1,2\n", false},
+		{"rows", [][]string{{"1", "x,y"}, {"2", `"q"`}}, nil, "1,\"x,y\"\n2,\"\"\"q\"\"\"\n", false},
+		{"rows with header", []any{[]any{1, true}}, []any{"n", "flag"}, "n,flag\n1,true\n", false},
+		{"empty list", []any{}, nil, "", false},
+		{"no list", "text", nil, "", true},
+		{"mixed records and scalars", []any{map[string]any{"a": 1}, 2}, nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToCSV(tt.data, tt.header...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToCSV() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ToCSV() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package dagote
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+/*
+tracker records files read and written during execution (safe for concurrent use).
+*/
+type tracker struct {
+	mu       sync.Mutex
+	read     map[string]bool
+	produced map[string]bool
+	roots    []string // readable directories (sandbox)
+}
+
+/*
+newTracker creates a tracker.
+*/
+func newTracker() *tracker {
+	return &tracker{read: make(map[string]bool), produced: make(map[string]bool)}
+}
+
+/*
+recordRead records file read.
+*/
+func (t *tracker) recordRead(filename string) {
+	if absPath, err := filepath.Abs(filename); err == nil {
+		filename = absPath
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.read[filename] = true
+}
+
+/*
+recordProduced records (additional) file written.
+*/
+func (t *tracker) recordProduced(path string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.produced[path] = true
+}
+
+/*
+addReadRoot adds readable directory (if not already known).
+*/
+func (t *tracker) addReadRoot(dir string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, root := range t.roots {
+		if root == dir {
+			return
+		}
+	}
+	t.roots = append(t.roots, dir)
+}
+
+/*
+readRoots returns the readable directories.
+*/
+func (t *tracker) readRoots() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.roots...)
+}
+
+/*
+readFiles returns the sorted list of files read.
+*/
+func (t *tracker) readFiles() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return sortedKeys(t.read)
+}
+
+/*
+producedFiles returns the sorted list of files written.
+*/
+func (t *tracker) producedFiles() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return sortedKeys(t.produced)
+}
+
+/*
+sortedKeys returns the sorted keys of a set.
+*/
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
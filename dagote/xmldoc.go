@@ -0,0 +1,386 @@
+package dagote
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+)
+
+/*
+XMLNode is a node (document, element, attribute) of a queryable XML document (see ReadXMLDoc).
+Document order, namespaces and the distinction between attributes and elements are preserved.
+*/
+type XMLNode struct {
+	node *xmlquery.Node
+	doc  *xmlDoc
+}
+
+/*
+xmlDoc holds the settings shared by all nodes of a document.
+*/
+type xmlDoc struct {
+	namespaces map[string]string // prefix -> namespace URI (used in XPath expressions)
+	attrPrefix string            // key prefix for attributes (Map)
+	textKey    string            // key for text of elements with attributes or child elements (Map)
+	forceArray map[string]bool   // element names always converted to lists (Map)
+	exprs      sync.Map          // compiled XPath expressions
+}
+
+/*
+parsedXMLDoc is the parsed document stored in the data cache.
+*/
+type parsedXMLDoc struct {
+	root       *xmlquery.Node
+	namespaces map[string]string // namespace declarations of document
+}
+
+/*
+ReadXMLDoc reads XML from file and returns the queryable document (see XPath, XPathAll).
+Options (optional): attrPrefix (default: '-'), textKey (default: '#text'),
+forceArray (list of (prefixed) element names), namespaces (map of prefix to URI, in addition to the document declarations).
+*/
+func (e *Engine) ReadXMLDoc(filename string, options ...map[string]any) (*XMLNode, error) {
+	if filename == "" {
+		return nil, errors.New("readXMLDoc needs a filename")
+	}
+	doc, err := newXMLDoc(options)
+	if err != nil {
+		return nil, fmt.Errorf("readXMLDoc: %w", err)
+	}
+	err = e.checkRead(filename)
+	if err != nil {
+		return nil, err
+	}
+	e.recordRead(filename)
+	var parsed *parsedXMLDoc
+	if cached, ok := e.lookupCache("xmldoc", filename); ok {
+		parsed = cached.(*parsedXMLDoc)
+	} else {
+		xmlRaw, err := e.readFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read XML file, file=[%v], error=[%w]", filename, err)
+		}
+		parsed, err = parseXMLDoc(xmlRaw, filename)
+		if err != nil {
+			return nil, err
+		}
+		e.storeCache("xmldoc", filename, parsed)
+	}
+
+	namespaces := make(map[string]string, len(parsed.namespaces)+len(doc.namespaces))
+	for prefix, uri := range parsed.namespaces {
+		namespaces[prefix] = uri
+	}
+	for prefix, uri := range doc.namespaces {
+		namespaces[prefix] = uri
+	}
+	doc.namespaces = namespaces
+	return &XMLNode{node: parsed.root, doc: doc}, nil
+}
+
+/*
+parseXMLDoc parses XML data into document tree and collects the namespace declarations.
+*/
+func parseXMLDoc(xmlRaw []byte, filename string) (*parsedXMLDoc, error) {
+	root, err := xmlquery.Parse(bytes.NewReader(xmlRaw))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse XML data, file=[%v], error=[%w]", filename, err)
+	}
+	namespaces := make(map[string]string)
+	var collect func(node *xmlquery.Node)
+	collect = func(node *xmlquery.Node) {
+		for _, attr := range node.Attr {
+			if attr.Name.Space == "xmlns" {
+				// first declaration of prefix wins
+				if _, exists := namespaces[attr.Name.Local]; !exists {
+					namespaces[attr.Name.Local] = attr.Value
+				}
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			collect(child)
+		}
+	}
+	collect(root)
+	return &parsedXMLDoc{root: root, namespaces: namespaces}, nil
+}
+
+/*
+newXMLDoc creates document settings from readXMLDoc options.
+*/
+func newXMLDoc(options []map[string]any) (*xmlDoc, error) {
+	doc := &xmlDoc{attrPrefix: "-", textKey: "#text", forceArray: make(map[string]bool), namespaces: make(map[string]string)}
+	for _, option := range options {
+		for key, value := range option {
+			switch key {
+			case "attrPrefix":
+				doc.attrPrefix = fmt.Sprint(value)
+			case "textKey":
+				doc.textKey = fmt.Sprint(value)
+			case "forceArray":
+				names, ok := ToList(value)
+				if !ok {
+					names = nil
+					for _, name := range strings.Split(fmt.Sprint(value), ",") {
+						names = append(names, name)
+					}
+				}
+				for _, name := range names {
+					doc.forceArray[strings.TrimSpace(fmt.Sprint(name))] = true
+				}
+			case "namespaces":
+				namespaces, ok := ToMap(value)
+				if !ok {
+					return nil, fmt.Errorf("option 'namespaces' must be a map of prefix to URI, value=[%v]", value)
+				}
+				for prefix, uri := range namespaces {
+					doc.namespaces[prefix] = fmt.Sprint(uri)
+				}
+			default:
+				return nil, fmt.Errorf("unsupported option, option=[%v], supported=[attrPrefix, textKey, forceArray, namespaces]", key)
+			}
+		}
+	}
+	return doc, nil
+}
+
+/*
+compile compiles XPath expression with the namespaces of the document (cached).
+*/
+func (d *xmlDoc) compile(expression string) (*xpath.Expr, error) {
+	if cached, ok := d.exprs.Load(expression); ok {
+		return cached.(*xpath.Expr), nil
+	}
+	expr, err := xpath.CompileWithNS(expression, d.namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile XPath expression, expression=[%v], error=[%w]", expression, err)
+	}
+	d.exprs.Store(expression, expr)
+	return expr, nil
+}
+
+/*
+XPath evaluates XPath expression relative to node. Returns the first node of a node set (nil if empty)
+or the value of expressions like count(...), string(...) (e.g. xpath "//entry/title" $doc).
+*/
+func XPath(expression string, node *XMLNode) (any, error) {
+	if node == nil {
+		return nil, errors.New("xpath needs a node (see readXMLDoc)")
+	}
+	expr, err := node.doc.compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	result := expr.Evaluate(xmlquery.CreateXPathNavigator(node.node))
+	iter, ok := result.(*xpath.NodeIterator)
+	if !ok {
+		return result, nil
+	}
+	if iter.MoveNext() {
+		return node.wrap(iter), nil
+	}
+	return nil, nil
+}
+
+/*
+XPathAll evaluates XPath expression relative to node and returns all nodes of the node set in document order.
+*/
+func XPathAll(expression string, node *XMLNode) ([]*XMLNode, error) {
+	if node == nil {
+		return nil, errors.New("xpathAll needs a node (see readXMLDoc)")
+	}
+	expr, err := node.doc.compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	iter, ok := expr.Evaluate(xmlquery.CreateXPathNavigator(node.node)).(*xpath.NodeIterator)
+	if !ok {
+		return nil, fmt.Errorf("XPath expression doesn't select a node set (use xpath), expression=[%v]", expression)
+	}
+	nodes := []*XMLNode{}
+	for iter.MoveNext() {
+		nodes = append(nodes, node.wrap(iter))
+	}
+	return nodes, nil
+}
+
+/*
+wrap returns current node of XPath iterator as node of same document.
+*/
+func (n *XMLNode) wrap(iter *xpath.NodeIterator) *XMLNode {
+	navigator := iter.Current().(*xmlquery.NodeNavigator)
+	current := navigator.Current()
+	if navigator.NodeType() == xpath.AttributeNode {
+		// attribute nodes are represented as nodes with text child
+		text := &xmlquery.Node{Type: xmlquery.TextNode, Data: navigator.Value()}
+		current = &xmlquery.Node{Parent: current, Type: xmlquery.AttributeNode, Data: navigator.LocalName(),
+			Prefix: navigator.Prefix(), NamespaceURI: navigator.NamespaceURL(), FirstChild: text, LastChild: text}
+	}
+	return &XMLNode{node: current, doc: n.doc}
+}
+
+/*
+Name returns the local name of element or attribute.
+*/
+func (n *XMLNode) Name() string {
+	return n.node.Data
+}
+
+/*
+Prefix returns the namespace prefix of element or attribute.
+*/
+func (n *XMLNode) Prefix() string {
+	return n.node.Prefix
+}
+
+/*
+Namespace returns the namespace URI of element or attribute.
+*/
+func (n *XMLNode) Namespace() string {
+	return n.node.NamespaceURI
+}
+
+/*
+Text returns the text content of node and all descendants.
+*/
+func (n *XMLNode) Text() string {
+	return n.node.InnerText()
+}
+
+/*
+String returns the text content (used when node is printed).
+*/
+func (n *XMLNode) String() string {
+	return n.Text()
+}
+
+/*
+Attr returns the value of attribute (name: local name or prefix:name), empty if not present.
+*/
+func (n *XMLNode) Attr(name string) string {
+	return n.node.SelectAttr(name)
+}
+
+/*
+Attrs returns all attributes (without namespace declarations) keyed by (prefixed) name.
+*/
+func (n *XMLNode) Attrs() map[string]string {
+	attrs := make(map[string]string)
+	for _, attr := range n.node.Attr {
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			continue
+		}
+		attrs[prefixedName(attr.Name.Space, attr.Name.Local)] = attr.Value
+	}
+	return attrs
+}
+
+/*
+Children returns the child elements in document order.
+*/
+func (n *XMLNode) Children() []*XMLNode {
+	children := []*XMLNode{}
+	for child := n.node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == xmlquery.ElementNode {
+			children = append(children, &XMLNode{node: child, doc: n.doc})
+		}
+	}
+	return children
+}
+
+/*
+Parent returns the parent node (nil for document).
+*/
+func (n *XMLNode) Parent() *XMLNode {
+	if n.node.Parent == nil {
+		return nil
+	}
+	return &XMLNode{node: n.node.Parent, doc: n.doc}
+}
+
+/*
+XML returns the XML representation of node (including node itself).
+*/
+func (n *XMLNode) XML() string {
+	return n.node.OutputXML(true)
+}
+
+/*
+Map converts node to 'map of any' (like readXML, but with the options of readXMLDoc).
+The document node is converted to the map of its root element.
+*/
+func (n *XMLNode) Map() map[string]any {
+	node := n.node
+	if node.Type == xmlquery.DocumentNode {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == xmlquery.ElementNode {
+				node = child
+				break
+			}
+		}
+	}
+	value := n.doc.convert(node)
+	if m, ok := value.(map[string]any); ok {
+		return m
+	}
+	// text only element
+	return map[string]any{n.doc.textKey: value}
+}
+
+/*
+convert converts element into 'map of any' or string (text only elements).
+Attributes and elements are keyed by prefixed name (e.g. 'a:id'), so equal local names of different namespaces don't collide.
+*/
+func (d *xmlDoc) convert(node *xmlquery.Node) any {
+	result := make(map[string]any)
+	for _, attr := range node.Attr {
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			continue
+		}
+		result[d.attrPrefix+prefixedName(attr.Name.Space, attr.Name.Local)] = attr.Value
+	}
+
+	// repeated elements (and elements of forceArray) are converted to lists
+	var text strings.Builder
+	children := make(map[string][]any)
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		switch child.Type {
+		case xmlquery.ElementNode:
+			name := prefixedName(child.Prefix, child.Data)
+			children[name] = append(children[name], d.convert(child))
+		case xmlquery.TextNode, xmlquery.CharDataNode:
+			text.WriteString(child.Data)
+		}
+	}
+	for name, values := range children {
+		if len(values) == 1 && !d.forceArray[name] {
+			result[name] = values[0]
+		} else {
+			result[name] = values
+		}
+	}
+
+	content := strings.TrimSpace(text.String())
+	if len(result) == 0 {
+		return content
+	}
+	if content != "" {
+		result[d.textKey] = content
+	}
+	return result
+}
+
+/*
+prefixedName returns 'prefix:name' (name if prefix is empty).
+*/
+func prefixedName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + ":" + name
+}
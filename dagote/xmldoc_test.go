@@ -0,0 +1,64 @@
+package dagote
+
+import (
+	"reflect"
+	"testing"
+)
+
+/*
+TestXMLNodeMap tests the conversion of XML nodes into 'map of any'.
+*/
+func TestXMLNodeMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		xml     string
+		xpath   string // node to convert (empty: document)
+		options map[string]any
+		want    map[string]any
+	}{
+		{"text only root", `<r>hello</r>`, "", nil, map[string]any{"#text": "hello"}},
+		{"text only element", `<r><t> hi </t></r>`, "//t", nil, map[string]any{"#text": "hi"}},
+		{"empty element", `<r><t/></r>`, "//t", nil, map[string]any{"#text": ""}},
+		{"attributes and text", `<r id="1">x</r>`, "", nil, map[string]any{"-id": "1", "#text": "x"}},
+		{"child elements", `<r><a>1</a><b>2</b></r>`, "", nil, map[string]any{"a": "1", "b": "2"}},
+		{"repeated elements", `<r><a>1</a><a>2</a></r>`, "", nil, map[string]any{"a": []any{"1", "2"}}},
+		{"force array", `<r><a>1</a></r>`, "", map[string]any{"forceArray": "a"}, map[string]any{"a": []any{"1"}}},
+		{"custom keys", `<r id="1">x</r>`, "", map[string]any{"attrPrefix": "@", "textKey": "_"}, map[string]any{"@id": "1", "_": "x"}},
+		{"custom text key of text only element", `<r>x</r>`, "", map[string]any{"textKey": "_"}, map[string]any{"_": "x"}},
+		{"namespace declarations dropped", `<r xmlns="urn:d" xmlns:a="urn:a"><x>1</x></r>`, "", nil, map[string]any{"x": "1"}},
+		{"prefixed attributes", `<r xmlns:a="urn:a" xmlns:b="urn:b" a:id="1" b:id="2" id="3"/>`, "", nil,
+			map[string]any{"-a:id": "1", "-b:id": "2", "-id": "3"}},
+		{"prefixed elements", `<r xmlns:a="urn:a" xmlns:b="urn:b"><a:x>1</a:x><b:x>2</b:x><x>3</x></r>`, "", nil,
+			map[string]any{"a:x": "1", "b:x": "2", "x": "3"}},
+		{"force array of prefixed element", `<r xmlns:a="urn:a"><a:x>1</a:x></r>`, "", map[string]any{"forceArray": "a:x"},
+			map[string]any{"a:x": []any{"1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parseXMLDoc([]byte(tt.xml), "test.xml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var options []map[string]any
+			if tt.options != nil {
+				options = append(options, tt.options)
+			}
+			doc, err := newXMLDoc(options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc.namespaces = parsed.namespaces
+			node := &XMLNode{node: parsed.root, doc: doc}
+			if tt.xpath != "" {
+				selected, err := XPath(tt.xpath, node)
+				if err != nil {
+					t.Fatal(err)
+				}
+				node = selected.(*XMLNode)
+			}
+			if got := node.Map(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Map() of %q = %v, want %v", tt.xml, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,85 +1,207 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+
+	"github.com/Klaus-Tockloth/dagote/dagote"
 )
 
+// stdinConsumed is set after stdin has been read
+var stdinConsumed bool
+
 /*
-determineDotData idetermines 'dot' (.) data from string or file.
+dotSource describes one source of 'dot' (.) data.
 */
-func determineDotData() (any, error) {
-	var dotdata any
-	var err error
+type dotSource struct {
+	name     string // name shown in banner
+	filename string // empty for data from string or stdin
+	data     []byte
+	dottype  string
+}
 
-	if *dotstring != "" {
-		// create temporary file (to unify dot data processing)
-		f, err := os.CreateTemp("", "dotstring.*.txt")
-		if err != nil {
-			return nil, fmt.Errorf("-dotstring: unable to create temporary file, error=[%v]", err)
-		}
-		tempFilename := f.Name()
-		defer os.Remove(tempFilename)
-		ds := strings.ReplaceAll(*dotstring, "\\n", "\n")
-		_, err = f.WriteString(ds)
-		if err != nil {
-			f.Close()
-			return nil, fmt.Errorf("-dotstring: unable to write to temporary file, file=[%v], error=[%v]", tempFilename, err)
-		}
-		err = f.Close()
-		if err != nil {
-			return nil, fmt.Errorf("-dotstring: unable to close temporary file, file=[%v], error=[%v]", tempFilename, err)
+/*
+determineDotData idetermines 'dot' (.) data from string and/or file(s) and loads it into engine.
+*/
+func determineDotData(opts *options, engine *dagote.Engine) error {
+	// build ordered list of dot sources (files first, string last)
+	var sources []dotSource
+	for _, arg := range opts.dotfiles {
+		dottype, filename := splitDotFile(arg)
+		if filename == "-" {
+			data, err := readStdin()
+			if err != nil {
+				return fmt.Errorf("-dotfile: unable to read stdin, error=[%w]", err)
+			}
+			sources = append(sources, dotSource{name: "stdin", data: data, dottype: dottype})
+			continue
 		}
-		*dotfile = tempFilename
+		sources = append(sources, dotSource{name: filename, filename: filename, dottype: dottype})
+	}
+	if opts.dotstring != "" {
+		ds := strings.ReplaceAll(opts.dotstring, "\\n", "\n")
+		sources = append(sources, dotSource{name: "-dotstring", data: []byte(ds)})
 	}
 
-	if *dotfile != "" {
-		switch strings.ToLower(*dottype) {
-		case "json":
-			dotdata, err = readJSON(*dotfile)
-			if err != nil {
-				return nil, fmt.Errorf("unable to transform dot file to JSON, file=[%v], error=[%v]", *dotfile, err)
-			}
-		case "yaml":
-			dotdata, err = readYAML(*dotfile)
-			if err != nil {
-				return nil, fmt.Errorf("unable to transform dot file to YAML, file=[%v], error=[%v]", *dotfile, err)
-			}
-		case "csv":
-			dotdata, err = readCSV(*dotfile)
-			if err != nil {
-				return nil, fmt.Errorf("unable to transform dot file to CSV, file=[%v], error=[%v]", *dotfile, err)
-			}
-		case "csvmap":
-			dotdata, err = readCSVMap(*dotfile)
-			if err != nil {
-				return nil, fmt.Errorf("unable to transform dot file to CSVMap, file=[%v], error=[%v]", *dotfile, err)
-			}
-		case "text":
-			dotdata, err = readText(*dotfile)
-			if err != nil {
-				return nil, fmt.Errorf("unable to transform dot file to TEXT, file=[%v], error=[%v]", *dotfile, err)
+	assignDotTypes(sources, opts.dottypes)
+
+	if len(sources) == 0 {
+		return nil
+	}
+	if len(sources) > 1 {
+		fmt.Fprintf(opts.logWriter, "Dot data sources (merge strategy for lists: %s):\n", opts.dotmerge)
+	} else {
+		fmt.Fprintf(opts.logWriter, "Dot data source:\n")
+	}
+	for _, source := range sources {
+		detection := ""
+		if source.dottype == "auto" {
+			detection = "content"
+			if source.filename != "" && dagote.DetectTypeByExtension(source.filename) != "" {
+				detection = "extension"
 			}
-		case "lines":
-			dotdata, err = readLines(*dotfile)
+		}
+
+		dottype := source.dottype
+		var err error
+		if source.filename != "" && engine.FS() != nil {
+			// dot files of command line are always read from local file system (not from template bundle)
+			source.data, err = os.ReadFile(source.filename)
 			if err != nil {
-				return nil, fmt.Errorf("unable to transform dot file to LINES, file=[%v], error=[%v]", *dotfile, err)
+				return fmt.Errorf("unable to read dot file, file=[%v], error=[%w]", source.filename, err)
 			}
-		case "xml":
-			dotdata, err = readXML(*dotfile)
-			if err != nil {
-				return nil, fmt.Errorf("unable to transform dot file to XML, file=[%v], error=[%v]", *dotfile, err)
+			engine.RecordRead(source.filename)
+			if dottype == "auto" && detection == "extension" {
+				dottype = dagote.DetectTypeByExtension(source.filename)
 			}
-		case "toml":
-			dotdata, err = readTOML(*dotfile)
-			if err != nil {
-				return nil, fmt.Errorf("unable to transform dot file to TOML, file=[%v], error=[%v]", *dotfile, err)
+			source.filename = ""
+		}
+		if source.filename != "" {
+			dottype, err = engine.LoadDotFile(source.filename, source.dottype)
+		} else {
+			if dottype == "auto" {
+				dottype = dagote.SniffType(source.data)
 			}
-		default:
-			return nil, fmt.Errorf("unsupported dot type, type=[%v]", *dottype)
+			err = engine.LoadDot(bytes.NewReader(source.data), dottype)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", source.name, err)
+		}
+
+		if detection != "" {
+			fmt.Fprintf(opts.logWriter, "- %s (%s, auto-detected by %s)\n", source.name, dottype, detection)
+		} else {
+			fmt.Fprintf(opts.logWriter, "- %s (%s)\n", source.name, dottype)
 		}
 	}
+	fmt.Fprintf(opts.logWriter, "\n")
 
-	return dotdata, nil
+	return nil
+}
+
+/*
+assignDotTypes assigns the dot types to the sources without bound type ('-dotfile=type:file'). The n-th given type
+applies to the n-th source, the last given type applies to all further sources except files with known extension.
+Sources without given type are detected ('auto') if they have a known file extension or come from stdin or
+'-dotstring', all other files are text.
+*/
+func assignDotTypes(sources []dotSource, dottypes []string) {
+	lastType := ""
+	for i := range sources {
+		dottype := ""
+		if i < len(dottypes) {
+			lastType = strings.ToLower(dottypes[i])
+			dottype = lastType
+		} else if dagote.DetectTypeByExtension(sources[i].filename) == "" {
+			dottype = lastType
+		}
+		if sources[i].dottype != "" {
+			// type bound to file
+			continue
+		}
+		sources[i].dottype = dottype
+		if dottype == "" {
+			sources[i].dottype = defaultDotType(sources[i].filename)
+		}
+	}
+}
+
+/*
+splitDotFile splits dot file argument of the form 'type:file' (e.g. 'toml:over.conf', 'json:-') into dot type and
+file name. Arguments without prefix of a known dot type are returned as file name (empty dot type).
+*/
+func splitDotFile(arg string) (string, string) {
+	prefix, filename, found := strings.Cut(arg, ":")
+	if found && isDotType(strings.ToLower(prefix)) {
+		return strings.ToLower(prefix), filename
+	}
+	return "", arg
+}
+
+/*
+isDotType checks whether dot type (lower case) is supported.
+*/
+func isDotType(dottype string) bool {
+	for _, supported := range dagote.DotTypes {
+		if dottype == supported {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+dotFileNames returns the file names of the dot file arguments (without bound dot types).
+*/
+func dotFileNames(args []string) []string {
+	filenames := make([]string, len(args))
+	for i, arg := range args {
+		_, filenames[i] = splitDotFile(arg)
+	}
+	return filenames
+}
+
+/*
+defaultDotType returns the dot type of a source without given type ('auto' for data from stdin or string
+and files with known extension, 'text' for all other files).
+*/
+func defaultDotType(filename string) string {
+	if filename == "" || dagote.DetectTypeByExtension(filename) != "" {
+		return "auto"
+	}
+	return "text"
+}
+
+/*
+readStdin reads all data from stdin (stdin can be consumed only once).
+*/
+func readStdin() ([]byte, error) {
+	if stdinConsumed {
+		return nil, errors.New("stdin already consumed")
+	}
+	stdinConsumed = true
+	return io.ReadAll(os.Stdin)
+}
+
+/*
+dotOptions converts the '-dotopt' settings (key=value) into an option map and validates it.
+*/
+func dotOptions(list stringList) (map[string]any, error) {
+	dotOpts := make(map[string]any, len(list))
+	for _, setting := range list {
+		key, value, found := strings.Cut(setting, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("option '-dotopt=%s' invalid (expected: key=value)", setting)
+		}
+		dotOpts[key] = value
+	}
+	_, err := dagote.ParseCSVOptions(dotOpts)
+	if err != nil {
+		return nil, fmt.Errorf("option '-dotopt': %w", err)
+	}
+	return dotOpts, nil
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+/*
+TestAssignDotTypes tests the assignment of dot types to dot sources (given types and defaults).
+*/
+func TestAssignDotTypes(t *testing.T) {
+	tests := []struct {
+		name      string
+		filenames []string // empty: stdin or string
+		dottypes  []string
+		want      []string
+	}{
+		{"known extensions", []string{"a.json", "b.yaml", "c.csv"}, nil, []string{"auto", "auto", "auto"}},
+		{"unknown extensions", []string{"notes.txt", "README"}, nil, []string{"text", "text"}},
+		{"stdin and string", []string{"", ""}, nil, []string{"auto", "auto"}},
+		{"mixed", []string{"a.toml", "notes.txt", ""}, nil, []string{"auto", "text", "auto"}},
+		{"explicit type", []string{"notes.txt"}, []string{"auto"}, []string{"auto"}},
+		{"last type applies to further sources", []string{"a.txt", "b.txt", "c.txt"}, []string{"json", "YAML"}, []string{"json", "yaml", "yaml"}},
+		{"empty type uses default", []string{"a.txt", "b.json"}, []string{""}, []string{"text", "auto"}},
+		{"last type keeps known extension", []string{"base.yaml", "over.toml", "notes.txt"}, []string{"yaml"}, []string{"yaml", "auto", "yaml"}},
+		{"bound type", []string{"toml:over.conf", "c.txt"}, []string{"json"}, []string{"toml", "json"}},
+		{"bound type of stdin", []string{"a.json", "yaml:-"}, nil, []string{"auto", "yaml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := make([]dotSource, len(tt.filenames))
+			for i, arg := range tt.filenames {
+				dottype, filename := splitDotFile(arg)
+				sources[i] = dotSource{name: filename, filename: filename, dottype: dottype}
+			}
+			assignDotTypes(sources, tt.dottypes)
+			got := make([]string, len(sources))
+			for i, source := range sources {
+				got[i] = source.dottype
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("assignDotTypes(%q, %q) = %q, want %q", tt.filenames, tt.dottypes, got, tt.want)
+			}
+		})
+	}
+}
+
+/*
+TestSplitDotFile tests the split of dot file arguments into bound dot type and file name.
+*/
+func TestSplitDotFile(t *testing.T) {
+	tests := []struct {
+		arg          string
+		wantType     string
+		wantFilename string
+	}{
+		{"data.json", "", "data.json"},
+		{"toml:over.conf", "toml", "over.conf"},
+		{"CSVMAP:export.txt", "csvmap", "export.txt"},
+		{"json:-", "json", "-"},
+		{"-", "", "-"},
+		{"unknown:file.txt", "", "unknown:file.txt"},
+		{`C:\data\a.json`, "", `C:\data\a.json`},
+	}
+	for _, tt := range tests {
+		dottype, filename := splitDotFile(tt.arg)
+		if dottype != tt.wantType || filename != tt.wantFilename {
+			t.Errorf("splitDotFile(%q) = %q, %q, want %q, %q", tt.arg, dottype, filename, tt.wantType, tt.wantFilename)
+		}
+	}
+}
@@ -1,23 +1,31 @@
-module klaus/json/dagote
+module github.com/Klaus-Tockloth/dagote
 
 go 1.19
 
 require (
 	github.com/Masterminds/sprig/v3 v3.2.2
+	github.com/antchfx/xmlquery v1.4.1
+	github.com/antchfx/xpath v1.3.1
 	github.com/clbanning/mxj/v2 v2.5.7
+	github.com/itchyny/gojq v0.12.13
+	github.com/ohler55/ojg v1.14.0
 	github.com/pelletier/go-toml/v2 v2.0.5
+	github.com/shopspring/decimal v1.3.1
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/Masterminds/goutils v1.1.1 // indirect
 	github.com/Masterminds/semver/v3 v3.1.1 // indirect
+	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/google/uuid v1.3.0 // indirect
 	github.com/huandu/xstrings v1.3.3 // indirect
 	github.com/imdario/mergo v0.3.13 // indirect
+	github.com/itchyny/timefmt-go v0.1.5 // indirect
 	github.com/mitchellh/copystructure v1.2.0 // indirect
 	github.com/mitchellh/reflectwalk v1.0.2 // indirect
-	github.com/shopspring/decimal v1.3.1 // indirect
 	github.com/spf13/cast v1.5.0 // indirect
 	golang.org/x/crypto v0.2.0 // indirect
+	golang.org/x/net v0.7.0 // indirect
+	golang.org/x/text v0.7.0 // indirect
 )
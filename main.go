@@ -38,10 +38,17 @@ Links:
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Klaus-Tockloth/dagote/dagote"
 )
 
 // general program info
@@ -53,113 +60,578 @@ var (
 	progInfo    = "Allows usage of arbitrary JSON, YAML, TOML, CSV, XML, TEXT in Go templates."
 )
 
-// command line parameters
+// command line parameters (render options and command specific parameters)
 var (
-	format     *string
-	templates  *string
-	outputFile *string
-	dotfile    *string
-	dotstring  *string
-	dottype    *string
+	cliOptions = newOptions()
+	watch      *bool
+	addr       *string
+	project    *string
+	conversion = &convertOptions{}
 )
 
+// subcommand (empty for default render command)
+var command string
+
+/*
+options describes the settings of one render invocation (command line parameters or job of project file).
+*/
+type options struct {
+	format      string
+	templates   string
+	outputFile  string
+	dotfiles    stringList
+	dotstring   string
+	dottypes    stringList
+	dotopts     stringList
+	dotmerge    string
+	dotkey      string
+	fanout      bool
+	templateDir string
+	outputDir   string
+	partials    string
+	copyGlobs   string
+	outputRoot  string
+	leftDelim   string
+	rightDelim  string
+	functions   string
+	dataPath    string
+	dataDir     string
+	allowRead   string
+	parallel    int
+
+	timeout        time.Duration
+	maxOutputBytes int64
+	maxReadBytes   int64
+	partialOutput  string
+
+	logWriter io.Writer         // progress messages
+	cache     *dagote.DataCache // shared data cache (nil = no caching)
+}
+
+/*
+newOptions creates options with default settings.
+*/
+func newOptions() *options {
+	return &options{
+		format:        "text",
+		dotmerge:      "replace",
+		dotkey:        "name",
+		leftDelim:     "{{",
+		rightDelim:    "}}",
+		functions:     "all",
+		dataPath:      "cwd",
+		parallel:      1,
+		partialOutput: "delete",
+		logWriter:     os.Stderr,
+	}
+}
+
 /*
 main starts this program.
 */
 func main() {
 	var err error
 
-	fmt.Printf("\nProgram:\n")
-	fmt.Printf("  Name    : %s\n", progName)
-	fmt.Printf("  Release : %s - %s\n", progVersion, progDate)
-	fmt.Printf("  Purpose : %s\n", progPurpose)
-	fmt.Printf("  Info    : %s\n\n", progInfo)
+	fmt.Fprintf(os.Stderr, "\nProgram:\n")
+	fmt.Fprintf(os.Stderr, "  Name    : %s\n", progName)
+	fmt.Fprintf(os.Stderr, "  Release : %s - %s\n", progVersion, progDate)
+	fmt.Fprintf(os.Stderr, "  Purpose : %s\n", progPurpose)
+	fmt.Fprintf(os.Stderr, "  Info    : %s\n\n", progInfo)
 
 	log.SetFlags(0)
 	log.SetPrefix("error: ")
 
-	format = flag.String("format", "text", "format type (text, html)")
-	templates = flag.String("templates", "", "name of input template(s) (list of files and/or globs)")
-	outputFile = flag.String("output", "", "name of output file")
-	dotfile = flag.String("dotfile", "", "dot data from file (injected into start template, accessible via .)")
-	dotstring = flag.String("dotstring", "", "dot data from string (injected into start template, accessible via .)")
-	dottype = flag.String("dottype", "text", "type of (file/string) dot data (json, yaml, toml, csv, csvmap, xml, text, lines)")
+	opts := cliOptions
+	flag.StringVar(&opts.format, "format", opts.format, "format type (text, html)")
+	flag.StringVar(&opts.templates, "templates", "", "name of input template(s) (list of files and/or globs, '-' for stdin, 'bundle.zip!glob' for zip archive)")
+	flag.StringVar(&opts.outputFile, "output", "", "name of output file ('-' for stdout, template for '-fanout')")
+	flag.Var(&opts.dotfiles, "dotfile", "dot data from `file` ('-' for stdin, 'type:file' binds dot type) (injected into start template, accessible via .) (repeatable)")
+	flag.StringVar(&opts.dotstring, "dotstring", "", "dot data from string (injected into start template, accessible via .)")
+	flag.Var(&opts.dottypes, "dottype", "`type` of (file/string) dot data (auto, json, yaml, yamlall, ndjson, toml, csv, csvmap, xml, text, lines) (repeatable) (default: auto for known file extensions, stdin and string, otherwise text)")
+	flag.Var(&opts.dotopts, "dotopt", "`key=value` option of csv/csvmap dot data and convert input (comma, comment, trim, header, stripBOM, lazyQuotes, variableFields, shortRows, longRows, duplicateHeaders, emptyHeaders) (repeatable)")
+	flag.StringVar(&opts.dotmerge, "dotmerge", opts.dotmerge, "list merge strategy for multiple dot data sources (replace, append, key)")
+	flag.StringVar(&opts.dotkey, "dotkey", opts.dotkey, "key identifying list elements for merge strategy 'key'")
+	flag.BoolVar(&opts.fanout, "fanout", false, "execute start template once per dot data record (list element), one output file per record")
+	flag.StringVar(&opts.templateDir, "templatedir", "", "name of input template directory (scaffolding mode, each file is a start template)")
+	flag.StringVar(&opts.outputDir, "outputdir", "", "name of output directory (scaffolding mode)")
+	flag.StringVar(&opts.partials, "partials", "", "partial template(s) shared by all templates in scaffolding mode (list of files and/or globs)")
+	flag.StringVar(&opts.copyGlobs, "copy", "", "files copied verbatim in scaffolding mode (list of globs, matched against relative path and file name)")
+	flag.StringVar(&opts.outputRoot, "outputroot", "", "root directory for files written by template functions 'writeFile', 'renderTo' (default: output directory)")
+	flag.StringVar(&opts.leftDelim, "leftdelim", opts.leftDelim, "left action delimiter of templates")
+	flag.StringVar(&opts.rightDelim, "rightdelim", opts.rightDelim, "right action delimiter of templates")
+	flag.StringVar(&opts.functions, "functions", opts.functions, "enabled function groups (all or list of: sprig, data, file, query, collection, encode, html, output)")
+	flag.StringVar(&opts.dataPath, "datapath", opts.dataPath, "base path for relative paths of data functions (cwd, start, template, datadir)")
+	flag.StringVar(&opts.dataDir, "datadir", "", "base directory for relative paths of data functions (implies '-datapath=datadir')")
+	flag.StringVar(&opts.allowRead, "allow-read", "", "additional readable directories for data functions (list of directories, default: template, dot file and data directories only)")
+	flag.IntVar(&opts.parallel, "parallel", opts.parallel, "number of workers for rendering multiple outputs ('-fanout', '-templatedir', jobs of command 'build'), 0 = number of CPUs")
+	flag.DurationVar(&opts.timeout, "timeout", 0, "maximum duration of each template execution (e.g. 30s, 5m), 0 = no limit")
+	flag.Int64Var(&opts.maxOutputBytes, "max-output-bytes", 0, "maximum size of each output file in bytes, 0 = no limit")
+	flag.Int64Var(&opts.maxReadBytes, "max-read-bytes", 0, "maximum size of each file read by data functions in bytes, 0 = no limit")
+	flag.StringVar(&opts.partialOutput, "partial-output", opts.partialOutput, "policy for partially written output files of failed executions (delete, keep)")
+	watch = flag.Bool("watch", false, "watch templates and data files, re-render on change")
+	addr = flag.String("addr", "localhost:8080", "listen address of preview server (command 'serve')")
+	project = flag.String("project", "", "project file describing render jobs (command 'build', default: dagote.yaml or dagote.toml)")
+	flag.StringVar(&conversion.input, "input", "", "name of input data file ('-' for stdin) (command 'convert')")
+	flag.StringVar(&conversion.from, "from", "auto", "type of input data (auto, json, yaml, yamlall, ndjson, toml, csv, csvmap, xml, text, lines) (command 'convert')")
+	flag.StringVar(&conversion.to, "to", "", "type of output data (json, yaml, toml, csv, xml, text, lines) (command 'convert', default: by extension of output file)")
+	flag.BoolVar(&conversion.pretty, "pretty", false, "indented JSON and XML output (command 'convert')")
+	flag.BoolVar(&conversion.sortKeys, "sortkeys", false, "sort keys of maps (command 'convert', default: key order of JSON, YAML, CSV input)")
+	flag.StringVar(&conversion.header, "header", "", "CSV output columns and their order (list of column names) (command 'convert')")
+	flag.StringVar(&conversion.root, "root", "", "root element name of XML output (command 'convert', default: root of XML input or 'doc')")
+
+	args := os.Args[1:]
+	if len(args) > 0 && (args[0] == "serve" || args[0] == "build" || args[0] == "convert") {
+		command = args[0]
+		args = args[1:]
+	}
 
 	flag.Usage = printUsage
-	flag.Parse()
+	_ = flag.CommandLine.Parse(args)
+	if command == "build" {
+		err = build(opts, *project, flag.Args())
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+	if command == "convert" {
+		conversion.output = opts.outputFile
+		err = convert(opts, conversion)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
 	if flag.NFlag() == 0 {
 		printUsage()
 	}
-	if *templates == "" {
-		log.Fatalf("option '-templates=list' required")
+	if command == "serve" {
+		if opts.templates == "" {
+			log.Fatalf("option '-templates=list' required")
+		}
+		if opts.templateDir != "" || opts.fanout || *watch {
+			log.Fatalf("command 'serve' can't be combined with options '-templatedir', '-fanout', '-watch'")
+		}
+		if opts.stdinUsed() {
+			log.Fatalf("command 'serve' can't be combined with stdin ('-')")
+		}
+		err = dagote.ValidateFunctionGroups(strings.Split(opts.functions, ","))
+		if err != nil {
+			log.Fatalf("option '-functions': %v", err)
+		}
+		err = opts.validateDataPath()
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		err = serve(opts, *addr)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+
+	err = opts.validate()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if *watch {
+		if opts.stdinUsed() || opts.outputFile == "-" {
+			log.Fatalf("option '-watch' can't be combined with stdin or stdout ('-')")
+		}
+		watchAndRun(func() ([]string, error) {
+			engine, err := run(opts)
+			if engine == nil {
+				return nil, err
+			}
+			return engine.ReadFiles(), err
+		})
+		return
+	}
+
+	_, err = run(opts)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "\n")
+}
+
+/*
+validate checks the options (command line parameters or job settings) for the render command.
+*/
+func (opts *options) validate() error {
+	if opts.templateDir != "" {
+		if opts.outputDir == "" {
+			return errors.New("option '-outputdir=directory' required for option '-templatedir=directory'")
+		}
+		if opts.templates != "" || opts.outputFile != "" || opts.fanout {
+			return errors.New("option '-templatedir=directory' can't be combined with options '-templates', '-output', '-fanout'")
+		}
+	} else {
+		if opts.templates == "" {
+			return errors.New("option '-templates=list' required")
+		}
+		if opts.outputFile == "" {
+			return errors.New("option '-output=file' required")
+		}
+	}
+	stdinUsers := 0
+	for _, filename := range append(strings.Split(opts.templates, ","), dotFileNames(opts.dotfiles)...) {
+		if filename == "-" {
+			stdinUsers++
+		}
+	}
+	if stdinUsers > 1 {
+		return errors.New("stdin ('-') can be used only once for option '-templates' or option '-dotfile'")
+	}
+	if len(opts.dottypes) > len(opts.dotfiles)+1 {
+		return errors.New("option '-dottype=string' given more often than dot data sources")
+	}
+
+	_, err := dotOptions(opts.dotopts)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(opts.dotmerge) {
+	case "replace", "append", "key":
+	default:
+		return fmt.Errorf("option '-dotmerge=%s' not supported", opts.dotmerge)
+	}
+
+	switch strings.ToLower(opts.partialOutput) {
+	case "delete", "keep":
+	default:
+		return fmt.Errorf("option '-partial-output=%s' not supported", opts.partialOutput)
+	}
+
+	err = dagote.ValidateFunctionGroups(strings.Split(opts.functions, ","))
+	if err != nil {
+		return fmt.Errorf("option '-functions': %w", err)
+	}
+	return opts.validateDataPath()
+}
+
+/*
+validateDataPath checks the options '-datapath' and '-datadir'.
+*/
+func (opts *options) validateDataPath() error {
+	mode := opts.dataPathMode()
+	switch mode {
+	case "cwd", "start", "template":
+		if opts.dataDir != "" {
+			return fmt.Errorf("option '-datadir' can't be combined with option '-datapath=%s'", mode)
+		}
+	case "datadir":
+		if opts.dataDir == "" {
+			return errors.New("option '-datadir=directory' required for option '-datapath=datadir'")
+		}
+	default:
+		return fmt.Errorf("option '-datapath=%s' not supported", opts.dataPath)
+	}
+	return nil
+}
+
+/*
+dataPathMode returns the base path mode of data functions ('-datadir' implies mode datadir).
+*/
+func (opts *options) dataPathMode() string {
+	mode := strings.ToLower(opts.dataPath)
+	if opts.dataDir != "" && (mode == "" || mode == "cwd") {
+		return "datadir"
+	}
+	return mode
+}
+
+/*
+stdinUsed checks whether stdin ('-') is used for templates or dot data.
+*/
+func (opts *options) stdinUsed() bool {
+	for _, filename := range append(strings.Split(opts.templates, ","), dotFileNames(opts.dotfiles)...) {
+		if filename == "-" {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+determineOutputRoot determines the output root for additional files (default: directory of output).
+*/
+func (opts *options) determineOutputRoot() string {
+	if opts.outputRoot != "" {
+		return opts.outputRoot
 	}
-	if *outputFile == "" {
-		log.Fatalf("option '-output=file' required")
+	switch {
+	case opts.templateDir != "":
+		return opts.outputDir
+	case opts.outputFile != "-" && !opts.fanout:
+		return filepath.Dir(opts.outputFile)
+	default:
+		return "."
 	}
-	if *dotfile != "" && *dotstring != "" {
-		log.Fatalf("use either option '-dotfile=file' or option '-dotstring=string'")
+}
+
+/*
+run processes (determine dot data, parse, execute) template file set or template directory once.
+Returns the engine used (nil if it could not be created), e.g. to determine the files read.
+*/
+func run(opts *options) (*dagote.Engine, error) {
+	bundles, err := openBundles(opts.templates, opts.partials)
+	if err != nil {
+		return nil, err
+	}
+	engine, err := newEngine(opts, bundles)
+	if err != nil {
+		return nil, err
 	}
 
-	dotdata, err := determineDotData()
+	err = determineDotData(opts, engine)
 	if err != nil {
-		log.Fatalf("unable to determine dot data, error=[%v]", err)
+		return engine, fmt.Errorf("unable to determine dot data, error=[%v]", err)
+	}
+
+	if opts.templateDir != "" {
+		err = processTemplateDir(opts, engine, bundles)
+		if err != nil {
+			return engine, fmt.Errorf("unable to process template directory, error=[%v]", err)
+		}
+		printProducedFiles(opts, engine)
+		return engine, nil
 	}
 
-	templateFiles, err := determineTemplateFiles()
+	templateFiles, err := determineTemplateFiles(opts.templates, bundles, opts.logWriter)
 	if err != nil {
-		log.Fatalf("unable to determine template file(s), error=[%v]", err)
+		return engine, fmt.Errorf("unable to determine template file(s), error=[%v]", err)
 	}
 
-	err = processTemplates(templateFiles, dotdata)
+	err = processTemplates(opts, engine, bundles, templateFiles)
 	if err != nil {
-		log.Fatalf("unable to process template(s), error=[%v]", err)
+		return engine, fmt.Errorf("unable to process template(s), error=[%v]", err)
 	}
+	printProducedFiles(opts, engine)
 
-	fmt.Printf("\n")
+	return engine, nil
+}
+
+/*
+stringList is a command line parameter which can be given multiple times.
+*/
+type stringList []string
+
+/*
+String returns the string representation of the list (flag.Value interface).
+*/
+func (sl *stringList) String() string {
+	return strings.Join(*sl, ",")
+}
+
+/*
+Set appends a value to the list (flag.Value interface).
+*/
+func (sl *stringList) Set(value string) error {
+	*sl = append(*sl, value)
+	return nil
 }
 
 /*
 printUsage prints the usage of this program.
 */
 func printUsage() {
-	fmt.Printf("Usage:\n")
-	fmt.Printf("  %s -templates=list -output=file [-format=string] [-dotfile=file | -dotstring=string] [-dottype=string]\n", os.Args[0])
-
-	fmt.Printf("\nExamples (single template):\n")
-	fmt.Printf("  %s -templates=test.tmpl -output=test.txt -format=text\n", os.Args[0])
-	fmt.Printf("  %s -templates=category.tmpl -output=category.html -format=html\n", os.Args[0])
-
-	fmt.Printf("\nExamples (set of templates):\n")
-	fmt.Printf("  %s -templates='test.tmpl,includes/*' -output=test.txt\n", os.Args[0])
-	fmt.Printf("  %s -templates='test.tmpl,templates/*.tmpl,includes/*' -output=test.txt\n", os.Args[0])
-
-	fmt.Printf("\nExamples (dot data from file):\n")
-	fmt.Printf("  %s -templates=test.tmpl -output=test.txt -dotfile=test.json -dottype=json\n", os.Args[0])
-	fmt.Printf("  %s -templates=test.tmpl -output=test.txt -dotfile=test.yaml -dottype=yaml\n", os.Args[0])
-
-	fmt.Printf("\nExamples (dot data from string):\n")
-	fmt.Printf("  %s -templates=test.tmpl -output=test.txt -dotstring='{\"forum\":\"meta.discourse.org\",\"topic\":69776}' -dottype=json\n", os.Args[0])
-	fmt.Printf("  %s -templates=test.tmpl -output=test.txt -dotstring='meta.discourse.org\\n69776' -dottype=lines\n", os.Args[0])
-	fmt.Printf("  %s -templates=test.tmpl -output=test.txt -dotstring='meta.discourse.org,69776' -dottype=csv\n", os.Args[0])
-	fmt.Printf("  %s -templates=test.tmpl -output=test.txt -dotstring='meta.discourse.org,69776' -dottype=text\n", os.Args[0])
-
-	fmt.Printf("\nNotes concerning option '-templates':\n")
-	fmt.Printf("  The templates list is a comma separates list of files and/or globs.\n")
-	fmt.Printf("  The globs in the templates list will be expanded to a list of files.\n")
-	fmt.Printf("  The first template in the list of files is the start template.\n")
-
-	fmt.Printf("\nNotes concerning options '-dotfile, -dotstring, -dottype':\n")
-	fmt.Printf("  These options allow to inject arbitrary data into the start template.\n")
-	fmt.Printf("  The injected data (.) can be considered as configuration or as content.\n")
-	fmt.Printf("    configuration: describes what to do and/or which data to load\n")
-	fmt.Printf("    content: represents the data to be processed within the template\n")
-	fmt.Printf("  -dotfile: file content represents the data to be injected\n")
-	fmt.Printf("  -dotstring: string content represents the data to be injected\n")
-	fmt.Printf("  -dottype: data (file/string) will be transformed into 'dottype'\n")
-
-	fmt.Printf("\nOptions:\n")
+	fmt.Fprintf(os.Stderr, "Usage:\n")
+	fmt.Fprintf(os.Stderr, "  %s -templates=list -output=file [-format=string] [-dotfile=file ...] [-dotstring=string] [-dottype=string ...] [-dotopt=key=value ...] [-dotmerge=string] [-dotkey=string] [-fanout] [-watch]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -templatedir=directory -outputdir=directory [-partials=list] [-copy=list] [-format=string] [-dotfile=file ...] [-dotstring=string] [-dottype=string ...]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s build [-project=file] [job ...]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s convert -input=file -output=file [-from=type] [-to=type] [-pretty] [-sortkeys] [-header=list] [-root=name] [-dotopt=key=value ...]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s serve -templates=list [-addr=host:port] [-format=string] [-dotfile=file ...] [-dotstring=string] [-dottype=string ...]\n", os.Args[0])
+
+	fmt.Fprintf(os.Stderr, "\nExamples (single template):\n")
+	fmt.Fprintf(os.Stderr, "  %s -templates=test.tmpl -output=test.txt -format=text\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -templates=category.tmpl -output=category.html -format=html\n", os.Args[0])
+
+	fmt.Fprintf(os.Stderr, "\nExamples (set of templates):\n")
+	fmt.Fprintf(os.Stderr, "  %s -templates='test.tmpl,includes/*' -output=test.txt\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -templates='test.tmpl,templates/*.tmpl,includes/*' -output=test.txt\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -templates='bundle.zip!templates/main.tmpl,bundle.zip!templates/*.tmpl' -output=test.txt\n", os.Args[0])
+
+	fmt.Fprintf(os.Stderr, "\nExamples (dot data from file):\n")
+	fmt.Fprintf(os.Stderr, "  %s -templates=test.tmpl -output=test.txt -dotfile=test.json -dottype=json\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -templates=test.tmpl -output=test.txt -dotfile=test.yaml -dottype=yaml\n", os.Args[0])
+
+	fmt.Fprintf(os.Stderr, "\nExamples (dot data from multiple sources):\n")
+	fmt.Fprintf(os.Stderr, "  %s -templates=test.tmpl -output=test.txt -dotfile=base.yaml -dottype=yaml -dotfile=prod.json -dottype=json\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -templates=test.tmpl -output=test.txt -dotfile=base.yaml -dotfile=prod.yaml -dottype=yaml -dotmerge=key -dotkey=id\n", os.Args[0])
+
+	fmt.Fprintf(os.Stderr, "\nExamples (pipeline usage with stdin and stdout):\n")
+	fmt.Fprintf(os.Stderr, "  curl -s https://example.com/data.json | %s -templates=test.tmpl -output=- -dotfile=- -dottype=json > test.txt\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  cat test.tmpl | %s -templates=- -output=- -dotfile=test.yaml | kubectl apply -f -\n", os.Args[0])
+
+	fmt.Fprintf(os.Stderr, "\nExamples (fan-out, one output file per dot data record):\n")
+	fmt.Fprintf(os.Stderr, "  %s -templates=item.tmpl -output='out/{{ .id }}.html' -format=html -dotfile=items.csv -dottype=csvmap -fanout\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -templates=item.tmpl -output='out/{{ .id }}.txt' -dotfile=export.csv -dottype=csvmap -dotopt=comma=';' -dotopt=header=2 -fanout\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -templates=item.tmpl -output='out/{{ .name | lower }}.txt' -dotfile=items.json -fanout\n", os.Args[0])
+
+	fmt.Fprintf(os.Stderr, "  %s -templates=item.tmpl -output='out/{{ .id }}.html' -format=html -dotfile=items.csv -dottype=csvmap -fanout -parallel=8\n", os.Args[0])
+
+	fmt.Fprintf(os.Stderr, "\nExamples (scaffolding, template directory tree -> output directory tree):\n")
+	fmt.Fprintf(os.Stderr, "  %s -templatedir=skeleton -outputdir=myproject -dotfile=project.yaml\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -templatedir=skeleton -outputdir=myproject -partials='skeleton/_partials/*' -copy='*.png,static/*' -dotfile=project.yaml\n", os.Args[0])
+
+	fmt.Fprintf(os.Stderr, "\nExamples (additional output files written within template):\n")
+	fmt.Fprintf(os.Stderr, "  {{ writeFile \"data/config.json\" (toJson .config) }}\n")
+	fmt.Fprintf(os.Stderr, "  {{ range .items }}{{ renderTo \"detail\" (printf \"items/%%v.html\" .id) . }}{{ end }}\n")
+
+	fmt.Fprintf(os.Stderr, "\nExamples (watch mode, re-render on change):\n")
+	fmt.Fprintf(os.Stderr, "  %s -templates=test.tmpl -output=test.txt -dotfile=test.yaml -watch\n", os.Args[0])
+
+	fmt.Fprintf(os.Stderr, "\nExamples (preview server with live reload):\n")
+	fmt.Fprintf(os.Stderr, "  %s serve -templates=category.tmpl -format=html -dotfile=category-67.json\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s serve -templates=category.tmpl -format=html -dotfile=category-67.json -addr=localhost:9000\n", os.Args[0])
+
+	fmt.Fprintf(os.Stderr, "\nExamples (project file with render jobs):\n")
+	fmt.Fprintf(os.Stderr, "  %s build\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s build -project=site.toml index category\n", os.Args[0])
+
+	fmt.Fprintf(os.Stderr, "\nExamples (data conversion without template):\n")
+	fmt.Fprintf(os.Stderr, "  %s convert -input=config.toml -output=config.json -pretty\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s convert -input=users.json -output=users.csv -header=name,email\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s convert -input=export.tsv -from=csvmap -output=export.json -dotopt=comma=tab -dotopt=comment=#\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  cat data.yaml | %s convert -input=- -from=yaml -output=- -to=xml -root=data -pretty\n", os.Args[0])
+
+	fmt.Fprintf(os.Stderr, "\nExamples (data file paths relative to template):\n")
+	fmt.Fprintf(os.Stderr, "  %s -templates=text-example/test.tmpl -output=test.txt -datapath=start\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -templates='site/page.tmpl,site/includes/*' -output=page.html -datapath=template\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -templates=test.tmpl -output=test.txt -datadir=data\n", os.Args[0])
+
+	fmt.Fprintf(os.Stderr, "\nExamples (additional readable directories for data functions):\n")
+	fmt.Fprintf(os.Stderr, "  %s -templates=test.tmpl -output=test.txt -allow-read=../shared,/opt/data\n", os.Args[0])
+
+	fmt.Fprintf(os.Stderr, "\nExamples (execution limits):\n")
+	fmt.Fprintf(os.Stderr, "  %s -templates=test.tmpl -output=test.txt -timeout=30s -max-output-bytes=10000000 -max-read-bytes=50000000\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s build -timeout=5m -partial-output=keep\n", os.Args[0])
+
+	fmt.Fprintf(os.Stderr, "\nExamples (dot data from string):\n")
+	fmt.Fprintf(os.Stderr, "  %s -templates=test.tmpl -output=test.txt -dotstring='{\"forum\":\"meta.discourse.org\",\"topic\":69776}' -dottype=json\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -templates=test.tmpl -output=test.txt -dotstring='meta.discourse.org\\n69776' -dottype=lines\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -templates=test.tmpl -output=test.txt -dotstring='meta.discourse.org,69776' -dottype=csv\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -templates=test.tmpl -output=test.txt -dotstring='meta.discourse.org,69776' -dottype=text\n", os.Args[0])
+
+	fmt.Fprintf(os.Stderr, "\nNotes concerning option '-templates':\n")
+	fmt.Fprintf(os.Stderr, "  The templates list is a comma separates list of files and/or globs.\n")
+	fmt.Fprintf(os.Stderr, "  The globs in the templates list will be expanded to a list of files.\n")
+	fmt.Fprintf(os.Stderr, "  The first template in the list of files is the start template.\n")
+	fmt.Fprintf(os.Stderr, "  The special name '-' reads a template from stdin (template name 'stdin').\n")
+	fmt.Fprintf(os.Stderr, "  The form 'bundle.zip!glob' expands the glob within the zip archive (template bundle).\n")
+	fmt.Fprintf(os.Stderr, "  With template bundles, data functions (readXXX, fileXXX) resolve relative paths within the\n")
+	fmt.Fprintf(os.Stderr, "  archive(s) first, then within the current directory (absolute paths and '..' are not supported).\n")
+
+	fmt.Fprintf(os.Stderr, "\nNotes concerning stdin and stdout:\n")
+	fmt.Fprintf(os.Stderr, "  '-templates=-', '-dotfile=-' read from stdin, '-output=-' writes to stdout.\n")
+	fmt.Fprintf(os.Stderr, "  Stdin can be used only once (either for a template or for dot data).\n")
+	fmt.Fprintf(os.Stderr, "  All program messages are written to stderr, stdout carries only the rendered result.\n")
+
+	fmt.Fprintf(os.Stderr, "\nNotes concerning options '-dotfile, -dotstring, -dottype':\n")
+	fmt.Fprintf(os.Stderr, "  These options allow to inject arbitrary data into the start template.\n")
+	fmt.Fprintf(os.Stderr, "  The injected data (.) can be considered as configuration or as content.\n")
+	fmt.Fprintf(os.Stderr, "    configuration: describes what to do and/or which data to load\n")
+	fmt.Fprintf(os.Stderr, "    content: represents the data to be processed within the template\n")
+	fmt.Fprintf(os.Stderr, "  -dotfile: file content represents the data to be injected\n")
+	fmt.Fprintf(os.Stderr, "  -dotstring: string content represents the data to be injected\n")
+	fmt.Fprintf(os.Stderr, "  -dottype: data (file/string) will be transformed into 'dottype'\n")
+	fmt.Fprintf(os.Stderr, "  -dottype=auto: type is detected by file extension (json, yaml, yml, toml, xml, csv, ndjson, jsonl) or by content\n")
+	fmt.Fprintf(os.Stderr, "  Without '-dottype' files with known extension, stdin and '-dotstring' are detected (auto), all other files are text.\n")
+
+	fmt.Fprintf(os.Stderr, "\nNotes concerning option '-dotopt' (CSV dialect of csv/csvmap dot data and convert input):\n")
+	fmt.Fprintf(os.Stderr, "  comma=char: field delimiter (default ',', names: tab, semicolon, pipe, space)\n")
+	fmt.Fprintf(os.Stderr, "  comment=char: lines starting with comment character are ignored\n")
+	fmt.Fprintf(os.Stderr, "  trim=true: leading and trailing white space of fields is removed\n")
+	fmt.Fprintf(os.Stderr, "  header=n: row number of header, rows before header are skipped (default 1)\n")
+	fmt.Fprintf(os.Stderr, "  stripBOM=false: UTF-8 byte order mark is kept (default: stripped)\n")
+	fmt.Fprintf(os.Stderr, "  lazyQuotes=true: quotes may appear in unquoted fields, non-doubled quotes in quoted fields\n")
+	fmt.Fprintf(os.Stderr, "  variableFields=true: records may have a variable number of fields (csv, maps use the row policies)\n")
+	fmt.Fprintf(os.Stderr, "  shortRows=pad: rows with fewer fields than header are padded with empty strings (default: error)\n")
+	fmt.Fprintf(os.Stderr, "  longRows=ignore: extra fields of rows longer than header are dropped (default: error)\n")
+	fmt.Fprintf(os.Stderr, "  duplicateHeaders=suffix: duplicate column names get a suffix, e.g. name_2 (default: error)\n")
+	fmt.Fprintf(os.Stderr, "  emptyHeaders=error|skip: empty column names fail or the column is ignored (default: name, e.g. column_3)\n")
+	fmt.Fprintf(os.Stderr, "  The same options are accepted by template functions 'readCSVWith' and 'readCSVMapWith' (as dict).\n")
+
+	fmt.Fprintf(os.Stderr, "\nNotes concerning multiple dot data sources:\n")
+	fmt.Fprintf(os.Stderr, "  Option '-dotfile' can be repeated, option '-dotstring' is always the last source.\n")
+	fmt.Fprintf(os.Stderr, "  The n-th '-dottype' belongs to the n-th source, the last '-dottype' applies to all further sources\n")
+	fmt.Fprintf(os.Stderr, "  except files with known extension. '-dotfile=type:file' binds the dot type to the file (e.g. toml:over.conf).\n")
+	fmt.Fprintf(os.Stderr, "  Sources are deep-merged in given order, later sources take precedence over earlier sources.\n")
+	fmt.Fprintf(os.Stderr, "  Maps are merged key by key, all other values are replaced by the later source.\n")
+	fmt.Fprintf(os.Stderr, "  A top level map can't be replaced by a later source which is not a map (error).\n")
+	fmt.Fprintf(os.Stderr, "  Lists are merged according to '-dotmerge':\n")
+	fmt.Fprintf(os.Stderr, "    replace: list of later source replaces list of earlier source\n")
+	fmt.Fprintf(os.Stderr, "    append: list of later source is appended to list of earlier source\n")
+	fmt.Fprintf(os.Stderr, "    key: list elements (maps) with same '-dotkey' value are merged, others are appended\n")
+
+	fmt.Fprintf(os.Stderr, "\nNotes concerning options '-datapath, -datadir':\n")
+	fmt.Fprintf(os.Stderr, "  Relative paths of data functions (readXXX, fileXXX) are resolved against a base path:\n")
+	fmt.Fprintf(os.Stderr, "    cwd: current working directory (default)\n")
+	fmt.Fprintf(os.Stderr, "    start: directory of start template\n")
+	fmt.Fprintf(os.Stderr, "    template: directory of template file defining the template which calls the data function\n")
+	fmt.Fprintf(os.Stderr, "    datadir: directory given by '-datadir'\n")
+	fmt.Fprintf(os.Stderr, "  Absolute paths are used as given, dot files are always relative to the current working directory.\n")
+
+	fmt.Fprintf(os.Stderr, "\nNotes concerning option '-allow-read':\n")
+	fmt.Fprintf(os.Stderr, "  Data functions (readXXX, fileXXX) can only read files below the readable directories:\n")
+	fmt.Fprintf(os.Stderr, "  directories of templates and dot files, base directory of '-datapath' and directories of '-allow-read'.\n")
+	fmt.Fprintf(os.Stderr, "  Symbolic links are resolved, other files are rejected with an 'access denied' error (naming template and line).\n")
+	fmt.Fprintf(os.Stderr, "  '-allow-read=/' allows reading of all files.\n")
+
+	fmt.Fprintf(os.Stderr, "\nNotes concerning options '-timeout, -max-output-bytes, -max-read-bytes, -partial-output':\n")
+	fmt.Fprintf(os.Stderr, "  '-timeout' limits each template execution, '-max-output-bytes' each output file (incl. 'writeFile', 'renderTo'),\n")
+	fmt.Fprintf(os.Stderr, "  '-max-read-bytes' each file read by data functions (and each dot file).\n")
+	fmt.Fprintf(os.Stderr, "  Exceeding a limit fails with a specific error (execution timeout / maximum output size / maximum read size exceeded).\n")
+	fmt.Fprintf(os.Stderr, "  Partially written output files of failed executions are deleted (default) or kept ('-partial-output=keep').\n")
+	fmt.Fprintf(os.Stderr, "  With command 'build' the limits given on the command line apply to all jobs without own setting.\n")
+
+	fmt.Fprintf(os.Stderr, "\nNotes concerning option '-fanout':\n")
+	fmt.Fprintf(os.Stderr, "  The dot data must be a list (csv, csvmap, lines, JSON/YAML array).\n")
+	fmt.Fprintf(os.Stderr, "  The start template is executed once per list element (record), the record is the dot data.\n")
+	fmt.Fprintf(os.Stderr, "  The output file name is a template, executed with the record as dot data.\n")
+	fmt.Fprintf(os.Stderr, "  The template set is parsed only once, missing output directories are created.\n")
+	fmt.Fprintf(os.Stderr, "  With '-parallel=N' the records are rendered by N workers (each with its own clone of the template set).\n")
+	fmt.Fprintf(os.Stderr, "  Log output and summary are printed in record order (deterministic).\n")
+
+	fmt.Fprintf(os.Stderr, "\nNotes concerning option '-templatedir':\n")
+	fmt.Fprintf(os.Stderr, "  Every file under the template directory is rendered into the output directory (tree to tree).\n")
+	fmt.Fprintf(os.Stderr, "  Every file is a start template, the dot data and the partial templates are shared by all files.\n")
+	fmt.Fprintf(os.Stderr, "  Path segments (file and directory names) are templates, executed with the dot data.\n")
+	fmt.Fprintf(os.Stderr, "  A path segment rendered to an empty name skips the file or directory.\n")
+	fmt.Fprintf(os.Stderr, "  The extension '.tmpl' is removed from output file names.\n")
+	fmt.Fprintf(os.Stderr, "  Files matching '-copy' are copied verbatim, partial templates are not rendered.\n")
+
+	fmt.Fprintf(os.Stderr, "\nNotes concerning option '-outputroot':\n")
+	fmt.Fprintf(os.Stderr, "  Template functions 'writeFile' and 'renderTo' write additional output files.\n")
+	fmt.Fprintf(os.Stderr, "  Relative paths are resolved against the output root, paths outside the output root are denied.\n")
+	fmt.Fprintf(os.Stderr, "  Default output root: directory of '-output' file, '-outputdir' or current directory.\n")
+	fmt.Fprintf(os.Stderr, "  All additional files written are listed in the final summary.\n")
+
+	fmt.Fprintf(os.Stderr, "\nNotes concerning option '-watch':\n")
+	fmt.Fprintf(os.Stderr, "  Watches all files used in the last execution (templates, dot files, files read within templates).\n")
+	fmt.Fprintf(os.Stderr, "  On change (debounced), the templates are parsed and executed again.\n")
+	fmt.Fprintf(os.Stderr, "  Errors are reported without terminating the program (terminate with Ctrl-C).\n")
+
+	fmt.Fprintf(os.Stderr, "\nNotes concerning command 'serve':\n")
+	fmt.Fprintf(os.Stderr, "  Starts a local preview server, the start template is rendered (in memory) on each request of '/'.\n")
+	fmt.Fprintf(os.Stderr, "  Sibling assets (e.g. main.css) are served from the directory of the start template.\n")
+	fmt.Fprintf(os.Stderr, "  A live reload script is injected into html output, the browser is refreshed when any\n")
+	fmt.Fprintf(os.Stderr, "  template, dot file or file read within the template set changes.\n")
+
+	fmt.Fprintf(os.Stderr, "\nNotes concerning command 'build':\n")
+	fmt.Fprintf(os.Stderr, "  Executes the named jobs (default: all jobs) of the project file (dagote.yaml or dagote.toml).\n")
+	fmt.Fprintf(os.Stderr, "  Each job describes one invocation (templates, format, dot sources, output, delimiters, functions).\n")
+	fmt.Fprintf(os.Stderr, "  Relative paths are resolved against the directory of the project file.\n")
+	fmt.Fprintf(os.Stderr, "  With '-parallel=N' the jobs are executed by N workers (job output and summary in job order).\n")
+	fmt.Fprintf(os.Stderr, "  Data files are parsed only once and shared by all jobs, a job summary is printed at the end.\n")
+
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
 	flag.PrintDefaults()
 
-	fmt.Printf("\n")
+	fmt.Fprintf(os.Stderr, "\n")
 	os.Exit(1)
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"sync"
+)
+
+/*
+numWorkers determines the number of workers (option '-parallel', 0 = number of CPUs) for a number of tasks.
+*/
+func numWorkers(parallel, tasks int) int {
+	workers := parallel
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > tasks {
+		workers = tasks
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return workers
+}
+
+/*
+taskResult holds log output and error of a task.
+*/
+type taskResult struct {
+	log  bytes.Buffer
+	err  error
+	done chan struct{}
+}
+
+/*
+runParallel executes tasks (0 ... count-1) with given number of workers.
+The log output of each task is buffered and written to log writer in task order (deterministic output).
+After an error, tasks with higher index are skipped. The error of the task with the lowest index is returned.
+*/
+func runParallel(logWriter io.Writer, workers, count int, task func(worker, index int, logWriter io.Writer) error) error {
+	results := make([]*taskResult, count)
+	for i := range results {
+		results[i] = &taskResult{done: make(chan struct{})}
+	}
+
+	var mu sync.Mutex
+	failedIndex := count // lowest index of failed task
+	indexes := make(chan int)
+	for w := 0; w < workers; w++ {
+		go func(worker int) {
+			for i := range indexes {
+				mu.Lock()
+				skip := i > failedIndex
+				mu.Unlock()
+				if !skip {
+					results[i].err = task(worker, i, &results[i].log)
+					if results[i].err != nil {
+						mu.Lock()
+						if i < failedIndex {
+							failedIndex = i
+						}
+						mu.Unlock()
+					}
+				}
+				close(results[i].done)
+			}
+		}(w)
+	}
+	go func() {
+		for i := 0; i < count; i++ {
+			indexes <- i
+		}
+		close(indexes)
+	}()
+
+	var firstErr error
+	for _, result := range results {
+		<-result.done
+		_, _ = logWriter.Write(result.log.Bytes())
+		if result.err != nil && firstErr == nil {
+			firstErr = result.err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Klaus-Tockloth/dagote/dagote"
+)
+
+/*
+processTemplateDir renders all files of template directory tree into output directory tree (scaffolding mode).
+Output directories are created on demand (empty directories are not reproduced).
+*/
+func processTemplateDir(opts *options, engine *dagote.Engine, bundles *bundleSet) error {
+	dotdata := engine.Dot()
+
+	// partial templates (shared by all start templates)
+	var partialFiles []string
+	var err error
+	if opts.partials != "" {
+		partialFiles, err = determineTemplateFiles(opts.partials, bundles, opts.logWriter)
+		if err != nil {
+			return fmt.Errorf("unable to determine partial template file(s), error=[%v]", err)
+		}
+	}
+	isPartial := make(map[string]bool)
+	for _, partialFile := range partialFiles {
+		absPath, err := filepath.Abs(partialFile)
+		if err != nil {
+			return fmt.Errorf("unable to determine absolute path, file=[%v], error=[%v]", partialFile, err)
+		}
+		isPartial[absPath] = true
+	}
+	if len(partialFiles) > 0 {
+		err = parseTemplates(opts, engine, bundles, partialFiles)
+		if err != nil {
+			return err
+		}
+	}
+
+	var copyPatterns []string
+	if opts.copyGlobs != "" {
+		copyPatterns = strings.Split(opts.copyGlobs, ",")
+	}
+
+	// determine tasks (in walk order)
+	var tasks []scaffoldTask
+	err = filepath.WalkDir(opts.templateDir, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(opts.templateDir, filename)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		absPath, err := filepath.Abs(filename)
+		if err != nil {
+			return err
+		}
+		if isPartial[absPath] {
+			return nil
+		}
+
+		outputPath, err := renderPath(engine, relPath, dotdata)
+		if err != nil {
+			return err
+		}
+		if outputPath == "" {
+			tasks = append(tasks, scaffoldTask{action: "skip", relPath: relPath})
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		task := scaffoldTask{action: "render", filename: filename, relPath: relPath, outputPath: filepath.Join(opts.outputDir, filepath.FromSlash(outputPath))}
+		if !isBelowDir(opts.outputDir, task.outputPath) {
+			return fmt.Errorf("rendered path outside of output directory, path=[%v], output=[%v]", relPath, task.outputPath)
+		}
+		if matchesAny(copyPatterns, relPath) {
+			task.action = "copy"
+		}
+		tasks = append(tasks, task)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	rendered, copied, skipped := 0, 0, 0
+	for _, task := range tasks {
+		switch task.action {
+		case "render":
+			rendered++
+		case "copy":
+			copied++
+		default:
+			skipped++
+		}
+	}
+
+	workers := numWorkers(opts.parallel, len(tasks))
+	fmt.Fprintf(opts.logWriter, "\nRendering %s template directory [%s] -> [%s] (%d worker(s)) ...\n", engine.Format(), opts.templateDir, opts.outputDir, workers)
+	err = runParallel(opts.logWriter, workers, len(tasks), func(worker, i int, logWriter io.Writer) error {
+		return tasks[i].execute(engine, logWriter)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(opts.logWriter, "Done (%d file(s) rendered, %d file(s) copied, %d skipped).\n", rendered, copied, skipped)
+
+	return nil
+}
+
+/*
+scaffoldTask describes the processing of one file of the template directory.
+*/
+type scaffoldTask struct {
+	action     string // render, copy, skip
+	filename   string
+	relPath    string
+	outputPath string
+}
+
+/*
+execute renders (or copies) the file of the template directory into the output directory.
+*/
+func (task scaffoldTask) execute(base *dagote.Engine, logWriter io.Writer) error {
+	if task.action == "skip" {
+		fmt.Fprintf(logWriter, "- %s (skipped)\n", task.relPath)
+		return nil
+	}
+
+	dir := filepath.Dir(task.outputPath)
+	err := os.MkdirAll(dir, 0777)
+	if err != nil {
+		return fmt.Errorf("unable to create output directory, directory=[%v], error=[%w]", dir, err)
+	}
+	if task.action == "copy" {
+		fmt.Fprintf(logWriter, "- %s -> %s (copied)\n", task.relPath, task.outputPath)
+		return copyFile(task.filename, task.outputPath)
+	}
+
+	engine, err := base.CloneWithFile(task.relPath, task.filename)
+	if err != nil {
+		return fmt.Errorf("unable to parse %s template, file=[%v], error=[%w]", base.Format(), task.filename, err)
+	}
+	fmt.Fprintf(logWriter, "- %s -> %s\n", task.relPath, task.outputPath)
+	return executeTemplate(engine, task.outputPath, engine.Dot())
+}
+
+/*
+renderPath renders each segment of relative (slash separated) path as template with dot data.
+The extension '.tmpl' is removed. An empty string is returned if any segment renders to an empty name,
+segments rendering to '.', '..' or to a name with path separator are rejected.
+*/
+func renderPath(engine *dagote.Engine, relPath string, dotdata any) (string, error) {
+	segments := strings.Split(relPath, "/")
+	for i, segment := range segments {
+		if strings.Contains(segment, engine.LeftDelim()) {
+			templ, err := engine.NewTextTemplate(segment, segment)
+			if err != nil {
+				return "", fmt.Errorf("unable to parse path segment template, path=[%v], error=[%w]", relPath, err)
+			}
+			var name strings.Builder
+			err = templ.Execute(&name, dotdata)
+			if err != nil {
+				return "", fmt.Errorf("unable to execute path segment template, path=[%v], error=[%w]", relPath, err)
+			}
+			segment = strings.TrimSpace(name.String())
+		}
+		if i == len(segments)-1 {
+			segment = strings.TrimSuffix(segment, ".tmpl")
+		}
+		if segment == "" {
+			return "", nil
+		}
+		if segment == "." || segment == ".." || strings.ContainsAny(segment, `/\`) {
+			return "", fmt.Errorf("path segment renders to invalid name (., .. or name with separator), path=[%v], segment=[%v]", relPath, segment)
+		}
+		segments[i] = segment
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+/*
+isBelowDir checks whether path is below directory dir (lexically).
+*/
+func isBelowDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+/*
+matchesAny checks whether relative path or its file name matches any of the glob patterns.
+*/
+func matchesAny(patterns []string, relPath string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if matched, _ := path.Match(pattern, relPath); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, path.Base(relPath)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+copyFile copies file verbatim (including permissions).
+*/
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("unable to open file, file=[%v], error=[%w]", src, err)
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("unable to stat file, file=[%v], error=[%w]", src, err)
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("unable to open output file, file=[%v], error=[%w]", dst, err)
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return fmt.Errorf("unable to copy file, file=[%v], error=[%w]", src, err)
+	}
+	err = out.Close()
+	if err != nil {
+		return fmt.Errorf("unable to close output file, file=[%v], error=[%w]", dst, err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Klaus-Tockloth/dagote/dagote"
+)
+
+/*
+TestRenderPath tests the rendering of template directory paths (skipped and rejected segments).
+*/
+func TestRenderPath(t *testing.T) {
+	engine, err := dagote.New()
+	if err != nil {
+		t.Fatalf("unable to create engine, error=[%v]", err)
+	}
+	dotdata := map[string]any{"n": "name", "empty": "", "up": "..", "cur": ".", "escape": "../escaped", "nested": "a/b", "backslash": `a\b`}
+	tests := []struct {
+		name    string
+		relPath string
+		want    string
+		wantErr bool
+	}{
+		{"plain path", "dir/file.txt.tmpl", "dir/file.txt", false},
+		{"templated segments", "{{ .n }}/{{ .n }}.txt.tmpl", "name/name.txt", false},
+		{"empty segment skips file", "{{ .empty }}/file.txt.tmpl", "", false},
+		{"parent directory", "{{ .up }}/file.txt.tmpl", "", true},
+		{"current directory", "{{ .cur }}/file.txt.tmpl", "", true},
+		{"parent directory in name", "{{ .escape }}.txt.tmpl", "", true},
+		{"slash in name", "dir/{{ .nested }}.txt.tmpl", "", true},
+		{"backslash in name", "dir/{{ .backslash }}.txt.tmpl", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderPath(engine, tt.relPath, dotdata)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderPath(%q) error = %v, wantErr %v", tt.relPath, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("renderPath(%q) = %q, want %q", tt.relPath, got, tt.want)
+			}
+		})
+	}
+}
+
+/*
+TestIsBelowDir tests the check whether a path is below a directory.
+*/
+func TestIsBelowDir(t *testing.T) {
+	tests := []struct {
+		dir  string
+		path string
+		want bool
+	}{
+		{"out", "out/a.txt", true},
+		{"out", "out/d/b", true},
+		{"out", "out", false},
+		{"out", "escaped.txt", false},
+		{"out", "out/../escaped.txt", false},
+		{"out", "out/..a.txt", true},
+	}
+	for _, tt := range tests {
+		if got := isBelowDir(tt.dir, tt.path); got != tt.want {
+			t.Errorf("isBelowDir(%q, %q) = %v, want %v", tt.dir, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Klaus-Tockloth/dagote/dagote"
+)
+
+// path of live reload endpoint (server-sent events)
+const liveReloadPath = "/__dagote/livereload"
+
+// script injected into html output (reloads page on server event)
+const liveReloadScript = `<script>new EventSource("` + liveReloadPath + `").onmessage = function() { location.reload(); };</script>`
+
+/*
+previewServer renders the template set on request and tracks the files used for live reload.
+*/
+type previewServer struct {
+	opts     *options
+	renderMu sync.Mutex // serializes rendering (templates may write additional files)
+
+	mu      sync.Mutex
+	files   []string // files read during last rendering
+	version int      // incremented on each detected change
+}
+
+/*
+serve starts the preview server (never returns without error).
+*/
+func serve(opts *options, addr string) error {
+	bundles, err := openBundles(opts.templates)
+	if err != nil {
+		return err
+	}
+	templateFiles, err := determineTemplateFiles(opts.templates, bundles, opts.logWriter)
+	if err != nil {
+		return fmt.Errorf("unable to determine template file(s), error=[%v]", err)
+	}
+	// assets are served from directory of start template (within zip archive for template bundles)
+	assetDir := filepath.Dir(templateFiles[0])
+	assetFS := http.FileSystem(http.Dir(assetDir))
+	if archive, name, ok := dagote.SplitArchivePath(templateFiles[0]); ok {
+		assetDir = archive + "!" + path.Dir(name)
+		fsys, err := fs.Sub(bundles.archives[archive], path.Dir(name))
+		if err != nil {
+			return fmt.Errorf("unable to determine asset directory, directory=[%v], error=[%v]", assetDir, err)
+		}
+		assetFS = http.FS(fsys)
+	}
+
+	server := &previewServer{opts: opts}
+	// initial rendering determines files to watch
+	_, err = server.render()
+	if err != nil {
+		fmt.Fprintf(opts.logWriter, "error: %v\n", err)
+	}
+	go server.watch()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(liveReloadPath, server.handleLiveReload)
+	assets := http.FileServer(assetFS)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			assets.ServeHTTP(w, r)
+			return
+		}
+		server.handleRender(w, r)
+	})
+
+	fmt.Fprintf(opts.logWriter, "\nServing [%s] on http://%s/ (assets from [%s], terminate with Ctrl-C) ...\n", templateFiles[0], addr, assetDir)
+	return http.ListenAndServe(addr, mux)
+}
+
+/*
+render determines dot data, parses and executes the template set into memory.
+*/
+func (s *previewServer) render() ([]byte, error) {
+	s.renderMu.Lock()
+	defer s.renderMu.Unlock()
+
+	bundles, err := openBundles(s.opts.templates)
+	if err != nil {
+		return nil, err
+	}
+	engine, err := newEngine(s.opts, bundles)
+	if err != nil {
+		return nil, err
+	}
+	defer s.updateFiles(engine)
+
+	err = determineDotData(s.opts, engine)
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine dot data, error=[%v]", err)
+	}
+	templateFiles, err := determineTemplateFiles(s.opts.templates, bundles, s.opts.logWriter)
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine template file(s), error=[%v]", err)
+	}
+	err = parseTemplates(s.opts, engine, bundles, templateFiles)
+	if err != nil {
+		return nil, fmt.Errorf("unable to process template(s), error=[%v]", err)
+	}
+	var buf bytes.Buffer
+	err = engine.Execute(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute %s template, name=[%v], error=[%v]", engine.Format(), engine.Name(), err)
+	}
+	printProducedFiles(s.opts, engine)
+	return buf.Bytes(), nil
+}
+
+/*
+updateFiles takes over the files read during last rendering as files to watch.
+*/
+func (s *previewServer) updateFiles(engine *dagote.Engine) {
+	files := engine.ReadFiles()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// keep files of previous rendering if rendering failed before reading any file
+	if len(files) > 0 {
+		s.files = files
+	}
+}
+
+/*
+watch polls the files of the last rendering and increments version on change (debounced).
+*/
+func (s *previewServer) watch() {
+	var last map[string]fileState
+	pending := false
+	unchanged := 0
+	for {
+		time.Sleep(watchPollInterval)
+		s.mu.Lock()
+		files := s.files
+		s.mu.Unlock()
+
+		current := snapshot(files)
+		changed := false
+		for filename, state := range current {
+			if previous, ok := last[filename]; ok && previous != state {
+				changed = true
+				fmt.Fprintf(s.opts.logWriter, "\nChange detected [%s] ...\n", filename)
+				break
+			}
+		}
+		last = current
+
+		if changed {
+			pending = true
+			unchanged = 0
+			continue
+		}
+		if pending {
+			unchanged++
+			if unchanged >= watchDebounce {
+				pending = false
+				s.mu.Lock()
+				s.version++
+				s.mu.Unlock()
+			}
+		}
+	}
+}
+
+/*
+handleRender renders the template set and injects the live reload script into html output.
+*/
+func (s *previewServer) handleRender(w http.ResponseWriter, r *http.Request) {
+	output, err := s.render()
+	if err != nil {
+		fmt.Fprintf(s.opts.logWriter, "error: %v\n", err)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "<!DOCTYPE html>\n<html><body><h1>dagote: rendering failed</h1><pre>%s</pre>%s</body></html>\n", html.EscapeString(err.Error()), liveReloadScript)
+		return
+	}
+
+	if s.opts.format != "html" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write(output)
+		return
+	}
+
+	page := string(output)
+	if i := strings.LastIndex(strings.ToLower(page), "</body>"); i >= 0 {
+		page = page[:i] + liveReloadScript + page[i:]
+	} else {
+		page += liveReloadScript
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(page))
+}
+
+/*
+handleLiveReload sends a server event to the browser when the version changes.
+*/
+func (s *previewServer) handleLiveReload(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher.Flush()
+
+	s.mu.Lock()
+	version := s.version
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			current := s.version
+			s.mu.Unlock()
+			if current != version {
+				fmt.Fprintf(w, "data: reload\n\n")
+				flusher.Flush()
+				return
+			}
+		}
+	}
+}
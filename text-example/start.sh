@@ -4,4 +4,4 @@
 # ------------------------------------
 
 set -o verbose
-dagote -templates='test.tmpl' -output=test.txt
+dagote -templates="$(dirname "$0")/test.tmpl" -output="$(dirname "$0")/test.txt" -datapath=start
@@ -2,24 +2,51 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
-	htmltemplate "html/template"
-	"log"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
-	texttemplate "text/template"
 
-	"github.com/Masterminds/sprig/v3"
+	"github.com/Klaus-Tockloth/dagote/dagote"
 )
 
 /*
-determineTemplateFiles determines template files for parsing.
+determineTemplateFiles determines template files for parsing (from list of files and/or globs).
+Entries of the form 'bundle.zip!glob' are expanded within the zip archive.
 */
-func determineTemplateFiles() ([]string, error) {
-	globs := strings.Split(*templates, ",")
+func determineTemplateFiles(list string, bundles *bundleSet, logWriter io.Writer) ([]string, error) {
+	globs := strings.Split(list, ",")
 	var templateFiles []string
 	for _, glob := range globs {
+		// template from stdin
+		if glob == "-" {
+			templateFiles = append(templateFiles, glob)
+			continue
+		}
+		// templates from zip archive
+		if archive, pattern, ok := dagote.SplitArchivePath(glob); ok {
+			fsys, err := bundles.archive(archive)
+			if err != nil {
+				return nil, err
+			}
+			matches, err := fs.Glob(fsys, pattern)
+			if err != nil {
+				return nil, fmt.Errorf("error [%v] at fs.Glob(), file=[%v]", err, archive)
+			}
+			for _, match := range matches {
+				info, err := fs.Stat(fsys, match)
+				if err != nil {
+					return nil, fmt.Errorf("error [%v] at fs.Stat(), file=[%v]", err, archive)
+				}
+				if !info.IsDir() {
+					templateFiles = append(templateFiles, archive+"!"+match)
+				}
+			}
+			continue
+		}
 		tmpfiles, err := filepath.Glob(glob)
 		if err != nil {
 			return nil, fmt.Errorf("error [%v] at filepath.Glob()", err)
@@ -39,135 +66,251 @@ func determineTemplateFiles() ([]string, error) {
 	if len(templateFiles) == 0 {
 		return nil, fmt.Errorf("no template file found for parsing")
 	}
-	fmt.Printf("Files for template parsing:\n")
+	fmt.Fprintf(logWriter, "Files for template parsing:\n")
 	for i := range templateFiles {
-		fmt.Printf("- %s\n", templateFiles[i])
+		fmt.Fprintf(logWriter, "- %s\n", templateFiles[i])
 	}
 
 	return templateFiles, nil
 }
 
+/*
+newEngine creates a template engine configured by options.
+*/
+func newEngine(opts *options, bundles *bundleSet) (*dagote.Engine, error) {
+	dotOpts, err := dotOptions(opts.dotopts)
+	if err != nil {
+		return nil, err
+	}
+	return dagote.New(
+		dagote.WithFS(bundles.overlay()),
+		dagote.WithFormat(opts.format),
+		dagote.WithDelims(opts.leftDelim, opts.rightDelim),
+		dagote.WithFunctions(strings.Split(opts.functions, ",")...),
+		dagote.WithMerge(opts.dotmerge, opts.dotkey),
+		dagote.WithDotOptions(dotOpts),
+		dagote.WithOutputRoot(opts.determineOutputRoot()),
+		dagote.WithDataCache(opts.cache),
+		dagote.WithDataPath(opts.dataPathMode(), opts.dataDir),
+		dagote.WithAllowRead(splitList(opts.allowRead)...),
+		dagote.WithTimeout(opts.timeout),
+		dagote.WithMaxOutputBytes(opts.maxOutputBytes),
+		dagote.WithMaxReadBytes(opts.maxReadBytes),
+		dagote.WithPartialOutput(opts.partialOutput),
+	)
+}
+
+/*
+splitList splits comma separated list (empty list for empty string).
+*/
+func splitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
+
 /*
 processTemplates processes (parse, execute) template file set.
 */
-func processTemplates(templateFiles []string, dotdata any) error {
-	var err error
-
-	*format = strings.ToLower(*format)
-	switch *format {
-	case "text":
-		// create text template with functions
-		templ := texttemplate.New(templateFiles[0]).Funcs(sprig.FuncMap()).Funcs(
-			texttemplate.FuncMap{
-				"readJSON":   readJSON,
-				"readYAML":   readYAML,
-				"readCSV":    readCSV,
-				"readCSVMap": readCSVMap,
-				"readText":   readText,
-				"readLines":  readLines,
-				"readXML":    readXML,
-				"readTOML":   readTOML,
-				"fileExists": fileExists,
-				"fileStat":   fileStat,
-				"fileRead":   fileRead,
-				"toTypeHTML": toTypeHTML,
-				"toTypeCSS":  toTypeCSS,
-				"toTypeJS":   toTypeJS,
-				"toTypeURL":  toTypeURL,
-			})
-
-		// parse template
-		fmt.Printf("\nParsing text template(s) ...\n")
-		templ, err = templ.ParseFiles(templateFiles...)
-		if err != nil {
-			return fmt.Errorf("unable to parse text template(s), error=[%v]", err)
-		}
+func processTemplates(opts *options, engine *dagote.Engine, bundles *bundleSet, templateFiles []string) error {
+	err := parseTemplates(opts, engine, bundles, templateFiles)
+	if err != nil {
+		return err
+	}
 
-		startTemplate := templ.Name()
-		fmt.Printf("\nTemplates defined after parsing:\n")
-		for _, template := range templ.Templates() {
-			fmt.Printf("-  %s\n", template.Name())
-		}
+	if opts.fanout {
+		return executeFanout(opts, engine)
+	}
 
-		// execute template
-		fmt.Printf("\nExecuting text template [%s] -> [%s] ...\n", startTemplate, *outputFile)
-		file, err := os.OpenFile(*outputFile, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0666)
-		if err != nil {
-			return fmt.Errorf("unable to open output file, file=[%v], error=[%v]", *outputFile, err)
+	fmt.Fprintf(opts.logWriter, "\nExecuting %s template [%s] -> [%s] ...\n", engine.Format(), engine.Name(), opts.outputFile)
+	err = executeTemplate(engine, opts.outputFile, engine.Dot())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(opts.logWriter, "Done.\n")
+
+	return nil
+}
+
+/*
+parseTemplates parses template file set (stdin, files, files of zip archives) as text or html templates (depending on format).
+The first file is the start template of the set.
+*/
+func parseTemplates(opts *options, engine *dagote.Engine, bundles *bundleSet, templateFiles []string) error {
+	fmt.Fprintf(opts.logWriter, "\nParsing %s template(s) ...\n", engine.Format())
+	for _, filename := range templateFiles {
+		var err error
+		if filename == "-" {
+			var data []byte
+			data, err = readStdin()
+			if err != nil {
+				return fmt.Errorf("unable to read template from stdin, error=[%w]", err)
+			}
+			err = engine.Parse("stdin", string(data))
+		} else if archive, name, ok := dagote.SplitArchivePath(filename); ok {
+			var fsys fs.FS
+			fsys, err = bundles.archive(archive)
+			if err == nil {
+				engine.RecordRead(archive)
+				err = engine.ParseTemplates(fsys, name)
+			}
+		} else {
+			err = engine.ParseFiles(filename)
 		}
-		writer := bufio.NewWriter(file)
-		err = templ.Execute(writer, dotdata)
 		if err != nil {
-			return fmt.Errorf("unable to execute text template, name=[%v], error=[%v]", startTemplate, err)
+			return fmt.Errorf("unable to parse %s template(s), error=[%v]", engine.Format(), err)
 		}
-		err = writer.Flush()
-		if err != nil {
-			log.Fatalf("unable to flush output file, file=[%v], error=[%v]", *outputFile, err)
+	}
+
+	fmt.Fprintf(opts.logWriter, "\nTemplates defined after parsing:\n")
+	for _, name := range engine.TemplateNames() {
+		fmt.Fprintf(opts.logWriter, "-  %s\n", name)
+	}
+	return nil
+}
+
+/*
+executeTemplate executes start template of template set with dot data into output file.
+*/
+func executeTemplate(engine *dagote.Engine, filename string, dotdata any) error {
+	file, err := createOutput(filename)
+	if err != nil {
+		return fmt.Errorf("unable to open output file, file=[%v], error=[%v]", filename, err)
+	}
+	writer := bufio.NewWriter(file)
+	err = engine.ExecuteData(writer, dotdata)
+	if err != nil {
+		if engine.PartialOutput() == "keep" {
+			_ = writer.Flush()
 		}
-		err = file.Close()
-		if err != nil {
-			return fmt.Errorf("unable to close output file, file=[%v], error=[%v]", *outputFile, err)
+		file.Close()
+		if engine.PartialOutput() == "delete" && filename != "-" {
+			os.Remove(filename)
 		}
-		fmt.Printf("Done.\n")
-
-	case "html":
-		// create html template with functions
-		templ := htmltemplate.New(templateFiles[0]).Funcs(sprig.FuncMap()).Funcs(
-			htmltemplate.FuncMap{
-				"readJSON":   readJSON,
-				"readYAML":   readYAML,
-				"readCSV":    readCSV,
-				"readCSVMap": readCSVMap,
-				"readText":   readText,
-				"readLines":  readLines,
-				"readXML":    readXML,
-				"readTOML":   readTOML,
-				"fileExists": fileExists,
-				"fileStat":   fileStat,
-				"fileRead":   fileRead,
-				"toTypeHTML": toTypeHTML,
-				"toTypeCSS":  toTypeCSS,
-				"toTypeJS":   toTypeJS,
-				"toTypeURL":  toTypeURL,
-			})
-
-		// parse template
-		fmt.Printf("\nParsing html template(s) ...\n")
-		templ, err = templ.ParseFiles(templateFiles...)
+		return fmt.Errorf("unable to execute %s template, name=[%v], error=[%v]", engine.Format(), engine.Name(), err)
+	}
+	err = writer.Flush()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("unable to flush output file, file=[%v], error=[%v]", filename, err)
+	}
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("unable to close output file, file=[%v], error=[%v]", filename, err)
+	}
+	return nil
+}
+
+/*
+executeFanout executes start template once per dot data record (list element) into one output file per record.
+The name of each output file is the result of the output template executed with the record as dot data.
+*/
+func executeFanout(opts *options, engine *dagote.Engine) error {
+	records, ok := dagote.ToList(engine.Dot())
+	if !ok {
+		return fmt.Errorf("fan-out requires dot data of type list (e.g. csv, csvmap, lines, JSON/YAML array), type=[%T]", engine.Dot())
+	}
+	if opts.outputFile == "-" {
+		return errors.New("fan-out requires output file name template, not stdout")
+	}
+
+	outputTempl, err := engine.NewTextTemplate("output", opts.outputFile)
+	if err != nil {
+		return fmt.Errorf("unable to parse output file name template, template=[%v], error=[%v]", opts.outputFile, err)
+	}
+
+	// determine (unique) output file names
+	filenames := make([]string, len(records))
+	written := make(map[string]int)
+	for i, record := range records {
+		var name strings.Builder
+		err = outputTempl.Execute(&name, record)
 		if err != nil {
-			return fmt.Errorf("unable to parse html template(s), error=[%v]", err)
+			return fmt.Errorf("unable to execute output file name template, record=[%d], error=[%v]", i+1, err)
 		}
-
-		startTemplate := templ.Name()
-		fmt.Printf("\nTemplates defined after parsing:\n")
-		for _, template := range templ.Templates() {
-			fmt.Printf("-  %s\n", template.Name())
+		filename := strings.TrimSpace(name.String())
+		if filename == "" {
+			return fmt.Errorf("output file name template results in empty name, record=[%d]", i+1)
+		}
+		if previous, exists := written[filename]; exists {
+			return fmt.Errorf("output file name not unique, file=[%v], records=[%d, %d]", filename, previous, i+1)
 		}
+		written[filename] = i + 1
+		filenames[i] = filename
+	}
 
-		// execute template
-		fmt.Printf("\nExecuting html template [%s] -> [%s] ...\n", startTemplate, *outputFile)
-		file, err := os.OpenFile(*outputFile, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0666)
+	// one clone of template set per worker
+	workers := numWorkers(opts.parallel, len(records))
+	clones := make([]*dagote.Engine, workers)
+	for i := range clones {
+		clones[i], err = engine.Clone()
 		if err != nil {
-			return fmt.Errorf("unable to open output file, file=[%v], error=[%v]", *outputFile, err)
+			return err
 		}
-		writer := bufio.NewWriter(file)
-		err = templ.Execute(writer, dotdata)
+	}
+
+	fmt.Fprintf(opts.logWriter, "\nExecuting %s template [%s] for %d record(s) -> [%s] (%d worker(s)) ...\n", engine.Format(), engine.Name(), len(records), opts.outputFile, workers)
+	err = runParallel(opts.logWriter, workers, len(records), func(worker, i int, logWriter io.Writer) error {
+		filename := filenames[i]
+		dir := filepath.Dir(filename)
+		err := os.MkdirAll(dir, 0777)
 		if err != nil {
-			return fmt.Errorf("unable to execute html template, name=[%v], error=[%v]", startTemplate, err)
+			return fmt.Errorf("unable to create output directory, directory=[%v], error=[%v]", dir, err)
 		}
-		err = writer.Flush()
+		err = executeTemplate(clones[worker], filename, records[i])
 		if err != nil {
-			return fmt.Errorf("unable to flush output file, file=[%v], error=[%v]", *outputFile, err)
+			return fmt.Errorf("record=[%d]: %w", i+1, err)
 		}
-		err = file.Close()
-		if err != nil {
-			return fmt.Errorf("unable to close output file, file=[%v], error=[%v]", *outputFile, err)
+		fmt.Fprintf(logWriter, "- %s\n", filename)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(opts.logWriter, "Done (%d file(s) written).\n", len(filenames))
+
+	return nil
+}
+
+/*
+printProducedFiles prints the list of additional files written by template functions.
+*/
+func printProducedFiles(opts *options, engine *dagote.Engine) {
+	producedFiles := engine.ProducedFiles()
+	if len(producedFiles) == 0 {
+		return
+	}
+	fmt.Fprintf(opts.logWriter, "\nAdditional files written by template(s) (%d):\n", len(producedFiles))
+	cwd, _ := os.Getwd()
+	for _, path := range producedFiles {
+		if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
 		}
-		fmt.Printf("Done.\n")
+		fmt.Fprintf(opts.logWriter, "- %s\n", path)
+	}
+}
 
-	default:
-		return fmt.Errorf("option '-format=%s' not supported", *format)
+/*
+createOutput creates (truncates) output file or returns stdout for '-'.
+*/
+func createOutput(filename string) (io.WriteCloser, error) {
+	if filename == "-" {
+		return nopWriteCloser{os.Stdout}, nil
 	}
+	return os.OpenFile(filename, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0666)
+}
+
+/*
+nopWriteCloser wraps a writer (e.g. stdout) which must not be closed.
+*/
+type nopWriteCloser struct {
+	io.Writer
+}
 
+/*
+Close does nothing.
+*/
+func (nopWriteCloser) Close() error {
 	return nil
 }
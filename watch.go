@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// timings for watch mode
+const (
+	watchPollInterval = 250 * time.Millisecond
+	watchDebounce     = 2 // number of unchanged polls before re-rendering
+)
+
+/*
+fileState describes the state of a watched file.
+*/
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+/*
+watchAndRun runs function, watches all files read (returned by function) and runs function again on change (never returns).
+If the function fails without reading any file, the files of the previous run are watched.
+*/
+func watchAndRun(run func() ([]string, error)) {
+	var files []string
+	for {
+		readFiles, err := run()
+		if err != nil {
+			log.Printf("%v", err)
+		}
+		if len(readFiles) > 0 {
+			files = readFiles
+		}
+
+		fmt.Fprintf(os.Stderr, "\nWatching %d file(s) for changes (terminate with Ctrl-C) ...\n", len(files))
+
+		changed := waitForChange(files)
+		fmt.Fprintf(os.Stderr, "\nChange detected [%s], re-rendering ...\n", changed)
+	}
+}
+
+/*
+waitForChange polls files until a change has been detected and the files are stable again (debouncing).
+Returns the name of the first changed file.
+*/
+func waitForChange(files []string) string {
+	last := snapshot(files)
+	changed := ""
+	unchanged := 0
+	for {
+		time.Sleep(watchPollInterval)
+		current := snapshot(files)
+		name := diffSnapshots(last, current)
+		last = current
+		if name != "" {
+			if changed == "" {
+				changed = name
+			}
+			unchanged = 0
+			continue
+		}
+		if changed != "" {
+			unchanged++
+			if unchanged >= watchDebounce {
+				return changed
+			}
+		}
+	}
+}
+
+/*
+snapshot determines the state of all files.
+*/
+func snapshot(files []string) map[string]fileState {
+	states := make(map[string]fileState, len(files))
+	for _, filename := range files {
+		info, err := os.Stat(filename)
+		if err != nil {
+			states[filename] = fileState{}
+			continue
+		}
+		states[filename] = fileState{exists: true, size: info.Size(), modTime: info.ModTime()}
+	}
+	return states
+}
+
+/*
+diffSnapshots returns the name of the first file with changed state (empty string if nothing changed).
+*/
+func diffSnapshots(last, current map[string]fileState) string {
+	names := make([]string, 0, len(current))
+	for filename := range current {
+		names = append(names, filename)
+	}
+	sort.Strings(names)
+	for _, filename := range names {
+		if last[filename] != current[filename] {
+			return filename
+		}
+	}
+	return ""
+}